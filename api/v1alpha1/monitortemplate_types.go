@@ -0,0 +1,89 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MonitorTemplateSpec defines the default monitor parameters of the resources using the template
+type MonitorTemplateSpec struct {
+	// Parameters are the default monitor parameters, keyed like the uptimerobot-monitor annotations without
+	// their prefix e.g. interval or alert_contacts. The annotations of a resource take precedence over them.
+	Parameters map[string]string `json:"parameters"`
+
+	// Selector applies the template to the resources with matching labels without them naming it.
+	// Templates without selector only apply to the resources naming them.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// ClusterMonitorTemplateSpec defines the default monitor parameters of the resources using the template
+type ClusterMonitorTemplateSpec struct {
+	MonitorTemplateSpec `json:",inline"`
+
+	// NamespaceSelector applies the template to the resources of the namespaces with matching labels without
+	// them naming it. When both selectors are set a resource has to match both.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=mt
+
+// MonitorTemplate is the Schema for the monitortemplates API, it supplies default monitor parameters to the
+// resources of its namespace.
+type MonitorTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec MonitorTemplateSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// MonitorTemplateList contains a list of MonitorTemplate
+type MonitorTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MonitorTemplate `json:"items"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster,shortName=cmt
+
+// ClusterMonitorTemplate is the Schema for the clustermonitortemplates API, it supplies default monitor
+// parameters to the resources of every namespace.
+type ClusterMonitorTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterMonitorTemplateSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterMonitorTemplateList contains a list of ClusterMonitorTemplate
+type ClusterMonitorTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterMonitorTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MonitorTemplate{}, &MonitorTemplateList{}, &ClusterMonitorTemplate{}, &ClusterMonitorTemplateList{})
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MonitorType is the type of UptimeRobot monitor.
+// +kubebuilder:validation:Enum=HTTP;HTTPS;Keyword;Ping;Port;Heartbeat
+type MonitorType string
+
+const (
+	HTTPMonitor      MonitorType = "HTTP"
+	HTTPSMonitor     MonitorType = "HTTPS"
+	KeywordMonitor   MonitorType = "Keyword"
+	PingMonitor      MonitorType = "Ping"
+	PortMonitor      MonitorType = "Port"
+	HeartbeatMonitor MonitorType = "Heartbeat"
+)
+
+const (
+	// ConditionReady is set when the monitor has been pushed to UptimeRobot.
+	ConditionReady = "Ready"
+)
+
+// UptimeRobotMonitorSpec defines the desired state of UptimeRobotMonitor
+type UptimeRobotMonitorSpec struct {
+	// FriendlyName of the monitor, it is used to identify the monitor on UptimeRobot.
+	// +kubebuilder:validation:MinLength=1
+	FriendlyName string `json:"friendlyName"`
+
+	// Type of the monitor.
+	Type MonitorType `json:"type"`
+
+	// URL or IP of the monitored resource.
+	// +kubebuilder:validation:MinLength=1
+	URL string `json:"url"`
+
+	// SubType is required by Port monitors.
+	// +kubebuilder:validation:Enum=HTTP;HTTPS;FTP;SMTP;POP3;IMAP
+	// +optional
+	SubType string `json:"subType,omitempty"`
+
+	// Port is required by Port monitors.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port *int32 `json:"port,omitempty"`
+
+	// Keyword is required by Keyword monitors.
+	// +optional
+	Keyword *KeywordSpec `json:"keyword,omitempty"`
+
+	// Interval between checks in seconds.
+	// +kubebuilder:validation:Minimum=30
+	// +optional
+	Interval *int32 `json:"interval,omitempty"`
+
+	// Timeout of a check in seconds.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=60
+	// +optional
+	Timeout *int32 `json:"timeout,omitempty"`
+
+	// AlertContacts to notify, each entry is an alert contact id (or friendly name when
+	// MONITOR_RESOLVE_ALERT_CONTACTS_BY_FRIENDLY_NAME is enabled) optionally followed by _threshold_recurrence.
+	// +optional
+	AlertContacts []string `json:"alertContacts,omitempty"`
+
+	// Parameters holds any other monitor parameter supported by https://uptimerobot.com/api/.
+	// The typed fields above take precedence over entries in this map. friendly_name, url and id are set by the
+	// operator and are rejected. http_method, post_value and post_content_type are not supported, they are not sent
+	// to UptimeRobot.
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// KeywordSpec configures a Keyword monitor.
+type KeywordSpec struct {
+	// Type of the keyword check.
+	// +kubebuilder:validation:Enum=exists;"not exists"
+	Type string `json:"type"`
+
+	// Value to look for in the response.
+	// +kubebuilder:validation:MinLength=1
+	Value string `json:"value"`
+
+	// CaseType of the keyword check.
+	// +kubebuilder:validation:Enum="case sensitive";"case insensitive"
+	// +optional
+	CaseType string `json:"caseType,omitempty"`
+}
+
+// UptimeRobotMonitorStatus defines the observed state of UptimeRobotMonitor
+type UptimeRobotMonitorStatus struct {
+	// MonitorID is the id of the monitor on UptimeRobot.
+	// +optional
+	MonitorID string `json:"monitorID,omitempty"`
+
+	// ObservedGeneration is the most recent generation pushed to UptimeRobot.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// LastSyncedTime is the last time the monitor was successfully pushed to UptimeRobot.
+	// +optional
+	LastSyncedTime *metav1.Time `json:"lastSyncedTime,omitempty"`
+
+	// Conditions of the monitor.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=urm
+//+kubebuilder:printcolumn:name="ID",type=string,JSONPath=`.status.monitorID`
+//+kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+//+kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.spec.url`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// UptimeRobotMonitor is the Schema for the uptimerobotmonitors API
+type UptimeRobotMonitor struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   UptimeRobotMonitorSpec   `json:"spec,omitempty"`
+	Status UptimeRobotMonitorStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// UptimeRobotMonitorList contains a list of UptimeRobotMonitor
+type UptimeRobotMonitorList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []UptimeRobotMonitor `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&UptimeRobotMonitor{}, &UptimeRobotMonitorList{})
+}
@@ -63,3 +63,94 @@ Create the name of the service account to use
 {{- default "default" .Values.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{/*
+Rules of the namespaced resources, granted by the ClusterRole or by a Role in each of .Values.watchNamespaces
+*/}}
+{{- define "uptimerobot-operator.namespacedRules" -}}
+- apiGroups:
+    - ""
+  resources:
+    - events
+  verbs:
+    - create
+    - patch
+- apiGroups:
+    - ""
+  resources:
+    - secrets
+  verbs:
+    - get
+    - list
+    - watch
+- apiGroups:
+    - ""
+  resources:
+    - services
+  verbs:
+    - get
+    - list
+    - patch
+    - update
+    - watch
+- apiGroups:
+    - gateway.networking.k8s.io
+  resources:
+    - gateways
+  verbs:
+    - get
+    - list
+    - watch
+- apiGroups:
+    - gateway.networking.k8s.io
+  resources:
+    - httproutes
+  verbs:
+    - get
+    - list
+    - patch
+    - update
+    - watch
+- apiGroups:
+    - monitoring.bennsimon.github.io
+  resources:
+    - monitortemplates
+  verbs:
+    - get
+    - list
+    - watch
+- apiGroups:
+    - monitoring.bennsimon.github.io
+  resources:
+    - uptimerobotmonitors
+  verbs:
+    - get
+    - list
+    - patch
+    - update
+    - watch
+- apiGroups:
+    - monitoring.bennsimon.github.io
+  resources:
+    - uptimerobotmonitors/finalizers
+  verbs:
+    - update
+- apiGroups:
+    - monitoring.bennsimon.github.io
+  resources:
+    - uptimerobotmonitors/status
+  verbs:
+    - get
+    - patch
+    - update
+- apiGroups:
+    - networking.k8s.io
+  resources:
+    - ingresses
+  verbs:
+    - get
+    - list
+    - patch
+    - update
+    - watch
+{{- end }}
@@ -0,0 +1,148 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// apiKeyRef is the key of a Secret holding an api key.
+type apiKeyRef struct {
+	types.NamespacedName
+	Key string
+}
+
+// APIKeyResolver resolves the api key of the UptimeRobot account of a resource from the Secret referenced by the
+// api-key-secret annotation of the resource, or else of its namespace. Resources without a reference use the
+// UPTIME_ROBOT_API_KEY env var.
+type APIKeyResolver struct {
+	// Client reads the namespaces, it should read from the manager's cache.
+	Client client.Reader
+	// SecretReader reads the referenced Secrets, it should not be cached so that the operator does not
+	// hold every Secret of the cluster in memory.
+	SecretReader client.Reader
+
+	mu   sync.Mutex
+	keys map[apiKeyRef]string
+}
+
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;watch;list
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;watch;list
+
+// Resolve returns the api key of the account of object, it is empty when the env var is to be used.
+func (r *APIKeyResolver) Resolve(ctx context.Context, object client.Object) (string, error) {
+	if r == nil {
+		return "", nil
+	}
+	annotationKey := monitorutil.GetUptimeRobotMonitorPrefix() + monitorutil.ApiKeySecretAnnotation
+	secretRef, exists := object.GetAnnotations()[annotationKey]
+	if !exists {
+		namespace := &corev1.Namespace{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Name: object.GetNamespace()}, namespace); err != nil {
+			return "", client.IgnoreNotFound(err)
+		}
+		if secretRef, exists = namespace.Annotations[annotationKey]; !exists {
+			return "", nil
+		}
+	}
+
+	name, key, found := strings.Cut(secretRef, "/")
+	if !found || len(name) == 0 || len(key) == 0 {
+		return "", fmt.Errorf("invalid %s annotation %q, expected <secret name>/<key>", annotationKey, secretRef)
+	}
+	ref := apiKeyRef{NamespacedName: types.NamespacedName{Namespace: object.GetNamespace(), Name: name}, Key: key}
+
+	r.mu.Lock()
+	apiKey, cached := r.keys[ref]
+	r.mu.Unlock()
+	if cached {
+		return apiKey, nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.SecretReader.Get(ctx, ref.NamespacedName, secret); err != nil {
+		return "", err
+	}
+	value, exists := secret.Data[key]
+	if !exists || len(value) == 0 {
+		return "", fmt.Errorf("secret %s has no api key %s", ref.NamespacedName, key)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.keys == nil {
+		r.keys = map[apiKeyRef]string{}
+	}
+	r.keys[ref] = string(value)
+	return string(value), nil
+}
+
+// Invalidate drops the cached api keys of the Secret so that they are read again on their next use.
+func (r *APIKeyResolver) Invalidate(secret types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for ref := range r.keys {
+		if ref.NamespacedName == secret {
+			delete(r.keys, ref)
+		}
+	}
+}
+
+func (r *APIKeyResolver) isCached(secret types.NamespacedName) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for ref := range r.keys {
+		if ref.NamespacedName == secret {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeySecretReconciler invalidates the cached api keys of a Secret when it is rotated or deleted. Only the
+// metadata of the Secrets is watched.
+type APIKeySecretReconciler struct {
+	Resolver *APIKeyResolver
+}
+
+func (r *APIKeySecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	r.Resolver.Invalidate(req.NamespacedName)
+	log.Log.Info(fmt.Sprintf("Api keys of secret %s invalidated", req.NamespacedName))
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *APIKeySecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("apikeysecret").
+		For(&corev1.Secret{}, builder.OnlyMetadata, builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
+			return r.Resolver.isCached(types.NamespacedName{Namespace: object.GetNamespace(), Name: object.GetName()})
+		}))).
+		Complete(r)
+}
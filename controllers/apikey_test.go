@@ -0,0 +1,104 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAPIKeyResolver_Resolve(t *testing.T) {
+	annotationKey := monitorutil.GetUptimeRobotMonitorPrefix() + monitorutil.ApiKeySecretAnnotation
+	objects := []client.Object{
+		&corev1.Namespace{ObjectMeta: ctrl.ObjectMeta{Name: "team-a", Annotations: map[string]string{annotationKey: "uptimerobot/api-key"}}},
+		&corev1.Namespace{ObjectMeta: ctrl.ObjectMeta{Name: "default"}},
+		&corev1.Secret{ObjectMeta: ctrl.ObjectMeta{Name: "uptimerobot", Namespace: "team-a"}, Data: map[string][]byte{"api-key": []byte("team-a-key")}},
+		&corev1.Secret{ObjectMeta: ctrl.ObjectMeta{Name: "uptimerobot", Namespace: "default"}, Data: map[string][]byte{"api-key": []byte("default-key")}},
+	}
+	tests := []struct {
+		name        string
+		namespace   string
+		annotations map[string]string
+		want        string
+		wantErr     bool
+	}{
+		{name: "should resolve api key of the resource annotation", namespace: "default", annotations: map[string]string{annotationKey: "uptimerobot/api-key"}, want: "default-key"},
+		{name: "should resolve api key of the namespace annotation", namespace: "team-a", want: "team-a-key"},
+		{name: "should fall back to env api key without reference", namespace: "default", want: ""},
+		{name: "should return error on invalid reference", namespace: "default", annotations: map[string]string{annotationKey: "uptimerobot"}, wantErr: true},
+		{name: "should return error on missing key", namespace: "default", annotations: map[string]string{annotationKey: "uptimerobot/other"}, wantErr: true},
+		{name: "should return error on missing secret", namespace: "default", annotations: map[string]string{annotationKey: "other/api-key"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).Build()
+			r := &APIKeyResolver{Client: c, SecretReader: c}
+			got, err := r.Resolve(context.Background(), &corev1.Service{ObjectMeta: ctrl.ObjectMeta{Name: "service", Namespace: tt.namespace, Annotations: tt.annotations}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyResolver_Invalidate(t *testing.T) {
+	annotationKey := monitorutil.GetUptimeRobotMonitorPrefix() + monitorutil.ApiKeySecretAnnotation
+	secret := &corev1.Secret{ObjectMeta: ctrl.ObjectMeta{Name: "uptimerobot", Namespace: "default"}, Data: map[string][]byte{"api-key": []byte("old-key")}}
+	c := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build()
+	r := &APIKeyResolver{Client: c, SecretReader: c}
+	object := &corev1.Service{ObjectMeta: ctrl.ObjectMeta{Name: "service", Namespace: "default", Annotations: map[string]string{annotationKey: "uptimerobot/api-key"}}}
+	resolve := func() string {
+		apiKey, err := r.Resolve(context.Background(), object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return apiKey
+	}
+
+	if got := resolve(); got != "old-key" {
+		t.Fatalf("Resolve() = %v, want %v", got, "old-key")
+	}
+	secret.Data["api-key"] = []byte("new-key")
+	if err := c.Update(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	if got := resolve(); got != "old-key" {
+		t.Errorf("Resolve() = %v, want cached %v", got, "old-key")
+	}
+
+	nn := types.NamespacedName{Namespace: "default", Name: "uptimerobot"}
+	if !r.isCached(nn) {
+		t.Errorf("isCached() = false, want true")
+	}
+	if _, err := (&APIKeySecretReconciler{Resolver: r}).Reconcile(context.Background(), ctrl.Request{NamespacedName: nn}); err != nil {
+		t.Fatal(err)
+	}
+	if got := resolve(); got != "new-key" {
+		t.Errorf("Resolve() = %v, want %v", got, "new-key")
+	}
+}
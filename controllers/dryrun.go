@@ -0,0 +1,329 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	DryRunCreate = "create"
+	DryRunUpdate = "update"
+	DryRunDelete = "delete"
+	DryRunPause  = "pause"
+	DryRunResume = "resume"
+
+	// dryRunEventPrefix marks the events of the dry-run mode, the monitors they report on are left untouched.
+	dryRunEventPrefix = "[dry-run] "
+)
+
+var dryRunPendingChangesDesc = prometheus.NewDesc("uptimerobot_operator_dry_run_pending_changes",
+	"Number of monitors the operator would create, update, delete, pause or resume if it was not running in dry-run mode.",
+	[]string{"operation"}, nil)
+
+// redactedParameters are left out of the logged payloads.
+var redactedParameters = []string{"http_password"}
+
+// DryRunUtilProvider is the UtilProvider of the dry-run mode. It logs the payloads of the monitors that would be
+// created, updated, deleted, paused or resumed instead of sending them, the read-only requests e.g. the drift checks
+// still go through UtilProvider so that the monitors to create are told from those to update. The monitors that would
+// change are exposed as metrics, a later change of the same monitor replaces its pending change. As nothing changes on
+// UptimeRobot, the outcomes of the read-only requests are kept in memory, a monitor is only checked again once its
+// annotations change.
+type DryRunUtilProvider struct {
+	UtilProvider
+
+	mu sync.Mutex
+	// pending changes by monitor url, or by id for the changes of monitors known by their id only. Monitors without
+	// pending change are known with an empty change.
+	pending map[string]string
+	// drifts of the last drift check by monitor url.
+	drifts map[string]dryRunDrift
+	// existing tells by id whether the monitors known by their id only exist.
+	existing map[string]bool
+}
+
+// dryRunDrift is the outcome of the drift check of a monitor with the annotations of the account of apiKey.
+type dryRunDrift struct {
+	apiKey      string
+	annotations map[string]string
+	drift       *monitorutil.Drift
+}
+
+var _ UtilProvider = &DryRunUtilProvider{}
+var _ prometheus.Collector = &DryRunUtilProvider{}
+
+// CreateMonitor reports the monitor that would be created, or updated when a monitor with its friendly name
+// exists, and returns the id of that monitor. Monitors that would be created have no id.
+func (p *DryRunUtilProvider) CreateMonitor(ctx context.Context, apiKey string, host string, annotations map[string]string) (string, error) {
+	payload, err := monitorutil.BuildMonitorPayload("", host, annotations)
+	if err != nil {
+		return "", err
+	}
+	drift, err := p.GetMonitorDrift(ctx, apiKey, host, annotations)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case drift.Missing:
+		p.report(host, DryRunCreate, payload)
+	case drift.HasDrift():
+		p.report(host, DryRunUpdate, payload)
+	default:
+		if p.resolve(host) {
+			log.Log.Info(fmt.Sprintf("Dry run: monitor %s of %s is up to date", drift.MonitorId, host))
+		}
+	}
+	return drift.MonitorId, nil
+}
+
+// GetMonitorDrift checks the drift of the monitor once for the annotations, later checks return the same drift.
+func (p *DryRunUtilProvider) GetMonitorDrift(ctx context.Context, apiKey string, host string, annotations map[string]string) (*monitorutil.Drift, error) {
+	p.mu.Lock()
+	checked, found := p.drifts[host]
+	p.mu.Unlock()
+	if found && checked.apiKey == apiKey && reflect.DeepEqual(checked.annotations, annotations) {
+		return checked.drift, nil
+	}
+	drift, err := p.UtilProvider.GetMonitorDrift(ctx, apiKey, host, annotations)
+	if err != nil {
+		return nil, err
+	}
+	checked = dryRunDrift{apiKey: apiKey, annotations: make(map[string]string, len(annotations)), drift: drift}
+	for key, value := range annotations {
+		checked.annotations[key] = value
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.drifts == nil {
+		p.drifts = map[string]dryRunDrift{}
+	}
+	p.drifts[host] = checked
+	return drift, nil
+}
+
+func (p *DryRunUtilProvider) UpdateMonitor(ctx context.Context, apiKey string, id string, host string, annotations map[string]string) (string, error) {
+	payload, err := monitorutil.BuildMonitorPayload(id, host, annotations)
+	if err != nil {
+		return "", err
+	}
+	p.report(host, DryRunUpdate, payload)
+	return id, nil
+}
+
+// DeleteMonitor reports the monitor that would be deleted, monitors that do not exist are not reported.
+func (p *DryRunUtilProvider) DeleteMonitor(ctx context.Context, apiKey string, host string, annotations map[string]string) error {
+	payload, err := monitorutil.BuildMonitorPayload("", host, annotations)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	created := p.pending[host] == DryRunCreate
+	p.mu.Unlock()
+	if created {
+		// the monitor was never created, there is nothing to delete.
+		p.resolve(host)
+		return nil
+	}
+	drift, err := p.GetMonitorDrift(ctx, apiKey, host, annotations)
+	if err != nil {
+		return err
+	}
+	if drift.Missing {
+		p.resolve(host)
+		return nil
+	}
+	p.report(host, DryRunDelete, payload)
+	return nil
+}
+
+// DeleteMonitorById reports the monitor that would be deleted, monitors that do not exist are not reported.
+func (p *DryRunUtilProvider) DeleteMonitorById(ctx context.Context, apiKey string, id string) error {
+	exists, err := p.exists(ctx, apiKey, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		p.resolve(id)
+		return nil
+	}
+	p.report(id, DryRunDelete, map[string]interface{}{"id": id})
+	return nil
+}
+
+func (p *DryRunUtilProvider) SetMonitorPausedById(ctx context.Context, apiKey string, id string, paused bool) error {
+	operation, status := DryRunResume, 1
+	if paused {
+		operation, status = DryRunPause, 0
+	}
+	p.report(id, operation, map[string]interface{}{"id": id, "status": status})
+	return nil
+}
+
+// PendingChanges counts the pending changes by operation.
+func (p *DryRunUtilProvider) PendingChanges() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	counts := map[string]int{}
+	for _, operation := range p.pending {
+		if len(operation) > 0 {
+			counts[operation]++
+		}
+	}
+	return counts
+}
+
+func (p *DryRunUtilProvider) Describe(descs chan<- *prometheus.Desc) {
+	descs <- dryRunPendingChangesDesc
+}
+
+func (p *DryRunUtilProvider) Collect(ch chan<- prometheus.Metric) {
+	counts := p.PendingChanges()
+	for _, operation := range []string{DryRunCreate, DryRunUpdate, DryRunDelete, DryRunPause, DryRunResume} {
+		ch <- prometheus.MustNewConstMetric(dryRunPendingChangesDesc, prometheus.GaugeValue, float64(counts[operation]), operation)
+	}
+}
+
+// exists checks once whether the monitor with the id exists.
+func (p *DryRunUtilProvider) exists(ctx context.Context, apiKey string, id string) (bool, error) {
+	p.mu.Lock()
+	exists, checked := p.existing[id]
+	p.mu.Unlock()
+	if checked {
+		return exists, nil
+	}
+	statuses, err := p.UtilProvider.GetMonitorStatuses(ctx, apiKey, []string{id})
+	if err != nil {
+		return false, err
+	}
+	exists = len(statuses) > 0
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.existing == nil {
+		p.existing = map[string]bool{}
+	}
+	p.existing[id] = exists
+	return exists, nil
+}
+
+// report logs the payload of the change and records it as pending for the monitor, a change that is already pending
+// is not logged again.
+func (p *DryRunUtilProvider) report(monitor string, operation string, payload map[string]interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pending[monitor] == operation {
+		return
+	}
+	if p.pending == nil {
+		p.pending = map[string]string{}
+	}
+	p.pending[monitor] = operation
+
+	parameters := make(map[string]interface{}, len(payload))
+	for key, value := range payload {
+		parameters[key] = value
+	}
+	for _, key := range redactedParameters {
+		if _, exists := parameters[key]; exists {
+			parameters[key] = "<redacted>"
+		}
+	}
+	log.Log.Info(fmt.Sprintf("Dry run: monitor %s would be %s", monitor, pastTense(operation)), "parameters", parameters)
+}
+
+// resolve drops the pending change of the monitor and reports whether it had one or was not known yet.
+func (p *DryRunUtilProvider) resolve(monitor string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	operation, known := p.pending[monitor]
+	if p.pending == nil {
+		p.pending = map[string]string{}
+	}
+	// up to date monitors are known without a pending change.
+	p.pending[monitor] = ""
+	return !known || len(operation) > 0
+}
+
+func pastTense(operation string) string {
+	switch operation {
+	case DryRunPause:
+		return "paused"
+	case DryRunResume:
+		return "resumed"
+	}
+	return operation + "d"
+}
+
+// DryRunRecorder marks the events of the dry-run mode, the reconcilers report the changes of the DryRunUtilProvider
+// as if they were made. The resources are synced again on every resync as their sync status is never written, each
+// event is only recorded once per resource so that the same changes are not reported again and again.
+type DryRunRecorder struct {
+	record.EventRecorder
+
+	mu sync.Mutex
+	// recorded events by resource.
+	recorded map[string]bool
+}
+
+var _ record.EventRecorder = &DryRunRecorder{}
+
+func (r *DryRunRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.firstRecord(object, eventtype, reason, message) {
+		r.EventRecorder.Event(object, eventtype, reason, dryRunEventPrefix+message)
+	}
+}
+
+func (r *DryRunRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.firstRecord(object, eventtype, reason, fmt.Sprintf(messageFmt, args...)) {
+		r.EventRecorder.Eventf(object, eventtype, reason, dryRunEventPrefix+messageFmt, args...)
+	}
+}
+
+func (r *DryRunRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.firstRecord(object, eventtype, reason, fmt.Sprintf(messageFmt, args...)) {
+		r.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, dryRunEventPrefix+messageFmt, args...)
+	}
+}
+
+// firstRecord reports whether the event was not recorded for object yet and records it.
+func (r *DryRunRecorder) firstRecord(object runtime.Object, eventtype, reason, message string) bool {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return true
+	}
+	key := fmt.Sprintf("%T/%s/%s/%s/%s/%s", object, accessor.GetUID(), accessor.GetNamespace(), accessor.GetName(), eventtype, reason) + "/" + message
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.recorded[key] {
+		return false
+	}
+	if r.recorded == nil {
+		r.recorded = map[string]bool{}
+	}
+	r.recorded[key] = true
+	return true
+}
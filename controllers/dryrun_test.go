@@ -0,0 +1,159 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	monitoringv1alpha1 "github.com/bennsimon/uptimerobot-operator/api/v1alpha1"
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	network "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDryRunUtilProvider(t *testing.T) {
+	annotations := map[string]string{
+		monitorutil.GetUptimeRobotDomain():                               "true",
+		monitorutil.GetUptimeRobotMonitorPrefix() + "friendly_name":      "tester",
+		monitorutil.GetUptimeRobotMonitorPrefix() + "http_password":      "secret",
+		monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation): monitorutil.StatusSynced,
+	}
+	testutilprovider := &testUtilProvider{}
+	testutilprovider.On("GetMonitorDrift", "", "https://new.local", annotations).Return(&monitorutil.Drift{Missing: true}, nil)
+	testutilprovider.On("GetMonitorDrift", "", "https://drifted.local", annotations).Return(&monitorutil.Drift{MonitorId: "2", Fields: []string{"interval"}}, nil)
+	testutilprovider.On("GetMonitorDrift", "", "https://synced.local", annotations).Return(&monitorutil.Drift{MonitorId: "3"}, nil)
+	testutilprovider.On("GetMonitorDrift", "", "https://gone.local", annotations).Return(&monitorutil.Drift{Missing: true}, nil)
+	testutilprovider.On("GetMonitorStatuses", "", []string{"6"}).Return([]monitorutil.MonitorStatus{{Id: "6"}}, nil)
+	testutilprovider.On("GetMonitorStatuses", "", []string{"7"}).Return([]monitorutil.MonitorStatus{}, nil)
+	defer testutilprovider.AssertExpectations(t)
+	p := &DryRunUtilProvider{UtilProvider: testutilprovider}
+
+	id, err := p.CreateMonitor(context.Background(), "", "https://new.local", annotations)
+	assert.NoError(t, err)
+	assert.Empty(t, id)
+	id, err = p.CreateMonitor(context.Background(), "", "https://drifted.local", annotations)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", id)
+	id, err = p.CreateMonitor(context.Background(), "", "https://synced.local", annotations)
+	assert.NoError(t, err)
+	assert.Equal(t, "3", id)
+	// the monitors are checked once, e.g. by the drift check of the sync.
+	_, err = p.CreateMonitor(context.Background(), "", "https://drifted.local", annotations)
+	assert.NoError(t, err)
+	testutilprovider.AssertNumberOfCalls(t, "GetMonitorDrift", 3)
+	id, err = p.UpdateMonitor(context.Background(), "", "4", "https://renamed.local", annotations)
+	assert.NoError(t, err)
+	assert.Equal(t, "4", id)
+	assert.NoError(t, p.SetMonitorPausedById(context.Background(), "", "5", true))
+	assert.NoError(t, p.DeleteMonitorById(context.Background(), "", "6"))
+	assert.NoError(t, p.DeleteMonitorById(context.Background(), "", "6"))
+	// monitors that do not exist would not be deleted.
+	assert.NoError(t, p.DeleteMonitorById(context.Background(), "", "7"))
+	assert.NoError(t, p.DeleteMonitor(context.Background(), "", "https://gone.local", annotations))
+	// the deletion of a monitor that would be created cancels its creation.
+	assert.NoError(t, p.DeleteMonitor(context.Background(), "", "https://new.local", annotations))
+	assert.NoError(t, p.DeleteMonitor(context.Background(), "", "https://synced.local", annotations))
+	testutilprovider.AssertNumberOfCalls(t, "GetMonitorStatuses", 2)
+
+	want := `
+# HELP uptimerobot_operator_dry_run_pending_changes Number of monitors the operator would create, update, delete, pause or resume if it was not running in dry-run mode.
+# TYPE uptimerobot_operator_dry_run_pending_changes gauge
+uptimerobot_operator_dry_run_pending_changes{operation="create"} 0
+uptimerobot_operator_dry_run_pending_changes{operation="delete"} 2
+uptimerobot_operator_dry_run_pending_changes{operation="pause"} 1
+uptimerobot_operator_dry_run_pending_changes{operation="resume"} 0
+uptimerobot_operator_dry_run_pending_changes{operation="update"} 2
+`
+	if err := testutil.CollectAndCompare(p, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDryRunRecorder(t *testing.T) {
+	fakeRecorder := record.NewFakeRecorder(100)
+	recorder := &DryRunRecorder{EventRecorder: fakeRecorder}
+	ingress := &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "ingress", Namespace: "default"}}
+	other := &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "other", Namespace: "default"}}
+
+	// the resources are synced again on every resync, the same events are only recorded once.
+	for i := 0; i < 2; i++ {
+		recorder.Event(ingress, "Normal", MonitorSyncedReason, "Monitor https://a.local successfully created/updated")
+		recorder.Eventf(ingress, "Normal", MonitorSyncedReason, "Monitor %s successfully created/updated", "https://b.local")
+		recorder.Event(other, "Normal", MonitorSyncedReason, "Monitor https://a.local successfully created/updated")
+	}
+	close(fakeRecorder.Events)
+	events := make([]string, 0)
+	for event := range fakeRecorder.Events {
+		events = append(events, event)
+	}
+	assert.Equal(t, []string{
+		"Normal MonitorSynced [dry-run] Monitor https://a.local successfully created/updated",
+		"Normal MonitorSynced [dry-run] Monitor https://b.local successfully created/updated",
+		"Normal MonitorSynced [dry-run] Monitor https://a.local successfully created/updated",
+	}, events)
+}
+
+// TestDryRun_resources makes sure that the resources synced in dry-run mode are neither given a finalizer nor marked
+// as synced, the monitors they would be synced with do not exist.
+func TestDryRun_resources(t *testing.T) {
+	ingress := &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "ingress", Namespace: "default", Annotations: map[string]string{
+		monitorutil.GetUptimeRobotDomain():                                     "true",
+		monitorutil.GetUptimeRobotMonitorPrefix() + httputil.FriendlyNameField: "tester",
+	}}}
+	c := fake.NewClientBuilder().WithScheme(newTemplateScheme(t)).WithObjects(ingress, newUptimeRobotMonitor()).Build()
+	testutilprovider := &testUtilProvider{}
+	testutilprovider.On("GetMonitorDrift", "", mock.Anything, mock.Anything).Return(&monitorutil.Drift{Missing: true}, nil)
+	defer testutilprovider.AssertExpectations(t)
+	p := &DryRunUtilProvider{UtilProvider: testutilprovider}
+	recorder := &DryRunRecorder{EventRecorder: record.NewFakeRecorder(100)}
+
+	syncer := &HostMonitorSyncer{Client: client.NewDryRunClient(c), Recorder: recorder, UtilProvider: p}
+	if _, err := syncer.Sync(context.Background(), ingress.DeepCopy(), IngressKind, map[string]string{"a.local": "http"}); err != nil {
+		t.Fatal(err)
+	}
+	got := &network.Ingress{}
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "ingress"}, got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Finalizers) > 0 || len(got.Annotations) != len(ingress.Annotations) {
+		t.Errorf("ingress finalizers = %v, annotations = %v, want it untouched", got.Finalizers, got.Annotations)
+	}
+
+	reconciler := &UptimeRobotMonitorReconciler{Client: client.NewDryRunClient(c), Recorder: recorder, ResyncPeriod: time.Hour, UtilProvider: p}
+	if _, err := reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "tester"}}); err != nil {
+		t.Fatal(err)
+	}
+	gotMonitor := &monitoringv1alpha1.UptimeRobotMonitor{}
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "tester"}, gotMonitor); err != nil {
+		t.Fatal(err)
+	}
+	if len(gotMonitor.Finalizers) > 0 || len(gotMonitor.Status.Conditions) > 0 || len(gotMonitor.Status.MonitorID) > 0 {
+		t.Errorf("monitor finalizers = %v, status = %v, want it untouched", gotMonitor.Finalizers, gotMonitor.Status)
+	}
+	assert.Equal(t, map[string]int{DryRunCreate: 2}, p.PendingChanges())
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	monitoringv1alpha1 "github.com/bennsimon/uptimerobot-operator/api/v1alpha1"
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	corev1 "k8s.io/api/core/v1"
+	network "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// Kinds of the resources that own monitors, they are part of the owner marker of the friendly names.
+const (
+	IngressKind            = "Ingress"
+	UptimeRobotMonitorKind = "UptimeRobotMonitor"
+	HTTPRouteKind          = "HTTPRoute"
+	ServiceKind            = "Service"
+)
+
+// ownerKinds returns an empty object of each kind of resource that owns monitors.
+var ownerKinds = map[string]func() client.Object{
+	IngressKind:            func() client.Object { return &network.Ingress{} },
+	UptimeRobotMonitorKind: func() client.Object { return &monitoringv1alpha1.UptimeRobotMonitor{} },
+	HTTPRouteKind:          func() client.Object { return &gatewayv1beta1.HTTPRoute{} },
+	ServiceKind:            func() client.Object { return &corev1.Service{} },
+}
+
+// MonitorGarbageCollector periodically deletes the monitors marked with ClusterId whose resource no longer exists,
+// e.g. because it was deleted while the operator was not running. Only the account of the UPTIME_ROBOT_API_KEY env
+// var is collected.
+type MonitorGarbageCollector struct {
+	// Client should read from the manager's cache, the resources are looked up for every marked monitor.
+	client.Client
+	ClusterId string
+	Interval  time.Duration
+	// DryRun only logs the orphaned monitors instead of deleting them.
+	DryRun bool
+	// Namespaces restricts the collection to the monitors of resources of these namespaces, the monitors of the
+	// other namespaces are kept since the cache does not hold their resources. Every namespace when empty.
+	Namespaces []string
+	UtilProvider
+}
+
+var _ manager.Runnable = &MonitorGarbageCollector{}
+var _ manager.LeaderElectionRunnable = &MonitorGarbageCollector{}
+
+func (g *MonitorGarbageCollector) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := g.collect(ctx); err != nil {
+			log.Log.Error(err, "Orphaned monitors not successfully garbage collected")
+		}
+	}, g.Interval)
+	return nil
+}
+
+// NeedLeaderElection makes sure that only one replica deletes monitors.
+func (g *MonitorGarbageCollector) NeedLeaderElection() bool {
+	return true
+}
+
+func (g *MonitorGarbageCollector) collect(ctx context.Context) error {
+	monitors, err := g.UtilProvider.ListMonitors(ctx, "", "["+g.ClusterId+"/")
+	if err != nil {
+		return err
+	}
+	for _, monitor := range monitors {
+		owner, found := monitorutil.GetOwner(monitor.FriendlyName, g.ClusterId)
+		if !found {
+			continue
+		}
+		orphaned, err := g.isOrphaned(ctx, owner)
+		if err != nil {
+			return err
+		}
+		if !orphaned {
+			continue
+		}
+		if g.DryRun {
+			log.Log.Info(fmt.Sprintf("Monitor %s is orphaned, %s %s/%s no longer exists", monitor.FriendlyName, owner.Kind, owner.Namespace, owner.Name))
+			continue
+		}
+		if err := g.UtilProvider.DeleteMonitorById(ctx, "", monitor.Id); err != nil && !monitorutil.IsMonitorNotFound(err) {
+			log.Log.Error(err, fmt.Sprintf("Orphaned monitor %s not successfully deleted", monitor.FriendlyName))
+			continue
+		}
+		log.Log.Info(fmt.Sprintf("Orphaned monitor %s successfully deleted", monitor.FriendlyName))
+	}
+	return nil
+}
+
+// isOrphaned reports whether the owner of a monitor no longer exists, monitors of unknown kinds, of kinds not
+// served by the cluster e.g. HTTPRoute without the Gateway API or outside of Namespaces are kept.
+func (g *MonitorGarbageCollector) isOrphaned(ctx context.Context, owner monitorutil.Owner) (bool, error) {
+	newObject, known := ownerKinds[owner.Kind]
+	if !known {
+		return false, nil
+	}
+	if len(g.Namespaces) > 0 && !containsString(g.Namespaces, owner.Namespace) {
+		return false, nil
+	}
+	err := g.Get(ctx, types.NamespacedName{Namespace: owner.Namespace, Name: owner.Name}, newObject())
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	monitoringv1alpha1 "github.com/bennsimon/uptimerobot-operator/api/v1alpha1"
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/stretchr/testify/mock"
+	network "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMonitorGarbageCollector_collect(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitoringv1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	monitors := []monitorutil.Monitor{
+		{Id: "1", FriendlyName: "live [prod/Ingress/default/live]"},
+		{Id: "2", FriendlyName: "orphan [prod/Ingress/default/deleted]"},
+		{Id: "3", FriendlyName: "orphan [prod/UptimeRobotMonitor/default/deleted]"},
+		{Id: "4", FriendlyName: "unknown [prod/Unknown/default/deleted]"},
+		{Id: "5", FriendlyName: "other cluster [staging/Ingress/default/deleted]"},
+		{Id: "6", FriendlyName: "not managed"},
+	}
+	var testutilprovider *testUtilProvider
+	tests := []struct {
+		name        string
+		dryRun      bool
+		namespaces  []string
+		wantError   bool
+		setupMocks  func()
+		verifyMocks func()
+	}{
+		{name: "should delete monitors of resources that no longer exist", setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("ListMonitors", "", "[prod/").Return(monitors, nil)
+			testutilprovider.On("DeleteMonitorById", "", "2").Return(nil)
+			testutilprovider.On("DeleteMonitorById", "", "3").Return(errors.New("some error"))
+		}, verifyMocks: func() {
+			testutilprovider.AssertExpectations(t)
+			testutilprovider.AssertNumberOfCalls(t, "DeleteMonitorById", 2)
+		}},
+		{name: "should not delete monitors of resources outside of the namespaces", namespaces: []string{"prod"}, setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("ListMonitors", "", "[prod/").Return(monitors, nil)
+		}, verifyMocks: func() {
+			testutilprovider.AssertExpectations(t)
+			testutilprovider.AssertNotCalled(t, "DeleteMonitorById", mock.Anything, mock.Anything)
+		}},
+		{name: "should not delete monitors on dry run", dryRun: true, setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("ListMonitors", "", "[prod/").Return(monitors, nil)
+		}, verifyMocks: func() {
+			testutilprovider.AssertExpectations(t)
+			testutilprovider.AssertNotCalled(t, "DeleteMonitorById", mock.Anything, mock.Anything)
+		}},
+		{name: "should return err when monitors cannot be listed", wantError: true, setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("ListMonitors", "", "[prod/").Return(nil, errors.New("some error"))
+		}, verifyMocks: func() {
+			testutilprovider.AssertExpectations(t)
+			testutilprovider.AssertNotCalled(t, "DeleteMonitorById", mock.Anything, mock.Anything)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+			defer tt.verifyMocks()
+
+			g := &MonitorGarbageCollector{
+				Client:       fake.NewClientBuilder().WithScheme(s).WithObjects(&network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "live", Namespace: "default"}}).Build(),
+				ClusterId:    "prod",
+				DryRun:       tt.dryRun,
+				Namespaces:   tt.namespaces,
+				UtilProvider: testutilprovider,
+			}
+			err := g.collect(context.Background())
+			if (err != nil) != tt.wantError {
+				t.Errorf("collect() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,652 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	monitoringv1alpha1 "github.com/bennsimon/uptimerobot-operator/api/v1alpha1"
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+	corev1 "k8s.io/api/core/v1"
+	network "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// HostMonitorSyncer syncs the monitors of resources configured through the uptimerobot-monitor annotations, a
+// monitor is created for each host of the resource. It is shared by the reconcilers of such resources.
+type HostMonitorSyncer struct {
+	client.Client
+	Recorder record.EventRecorder
+	// ResyncPeriod is the period after which synced monitors are checked for drift, zero disables it.
+	ResyncPeriod time.Duration
+	// FriendlyNameTemplate names the monitor of each host of resources with more than one host,
+	// monitorutil.DefaultFriendlyNameTemplate is used when nil.
+	FriendlyNameTemplate *template.Template
+	// ClusterId marks the friendly names of the monitors with their resource so that orphans can be garbage
+	// collected, monitors are not marked when empty.
+	ClusterId string
+	// APIKeyResolver resolves the UptimeRobot account of each resource, the UPTIME_ROBOT_API_KEY env var is used
+	// for every resource when nil.
+	APIKeyResolver *APIKeyResolver
+	// TemplateResolver resolves the default monitor parameters of each resource, templates are not used when nil.
+	TemplateResolver *TemplateResolver
+	// LabelSelector restricts the managed monitors to those of the resources with matching labels, the monitors of
+	// every resource are managed when nil.
+	LabelSelector labels.Selector
+	// NamespaceSelector restricts the managed monitors to those of the resources of namespaces with matching
+	// labels, the monitors of every namespace are managed when nil.
+	NamespaceSelector labels.Selector
+	// IngressClassFilter restricts the managed monitors of ingresses by their class and enables the monitors of the
+	// ingresses of its auto enabled classes, the class of ingresses is ignored when nil.
+	IngressClassFilter *IngressClassFilter
+	// OnDisable is the policy applied to the monitors of resources whose monitoring is disabled unless they set the
+	// on-disable annotation, monitorutil.OnDisableDelete or monitorutil.OnDisablePause. They are deleted when empty.
+	OnDisable string
+	UtilProvider
+}
+
+// Sync creates or updates the monitors of the hosts of object, or deletes them once object is being deleted.
+// hosts maps each host, optionally followed by the path to monitor, to its scheme. Hosts without a scheme are
+// monitored by their bare address e.g. Port monitors.
+func (r *HostMonitorSyncer) Sync(ctx context.Context, object client.Object, kind string, hosts map[string]string) (ctrl.Result, error) {
+	finalizer := monitorutil.GetUptimeRobotFinalizer()
+	if !object.GetDeletionTimestamp().IsZero() {
+		if !controllerutil.ContainsFinalizer(object, finalizer) {
+			return ctrl.Result{}, nil
+		}
+		apiKey, err := r.APIKeyResolver.Resolve(ctx, object)
+		if err != nil {
+			return r.releaseWithoutApiKey(ctx, object, kind, MonitorDeleteFailedReason, err)
+		}
+		// returning the error requeues the resource with the controller's exponential backoff,
+		// the finalizer is only removed once the monitors are gone.
+		if err := r.deleteMonitors(ctx, apiKey, object, kind, hosts); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(object, finalizer)
+		return ctrl.Result{}, r.Update(ctx, object)
+	}
+
+	if !r.isManaged(object) {
+		if r.hasPendingDisable(object) {
+			return r.disable(ctx, object, kind, hosts)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !controllerutil.ContainsFinalizer(object, finalizer) {
+		controllerutil.AddFinalizer(object, finalizer)
+		if err := r.Update(ctx, object); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	apiKey, err := r.APIKeyResolver.Resolve(ctx, object)
+	if err != nil {
+		log.Log.Error(err, fmt.Sprintf("Api key of %s %s/%s not successfully resolved", kind, object.GetNamespace(), object.GetName()))
+		r.Recorder.Event(object, corev1.EventTypeWarning, MonitorSyncFailedReason, fmt.Sprintf("Api key not successfully resolved: %s", err))
+		if err := patchStatusAnnotations(ctx, r.Client, object, monitorutil.StatusFailed, nil, ""); err != nil {
+			return ctrl.Result{}, err
+		}
+		// the referenced Secret may not be created yet.
+		return ctrl.Result{}, err
+	}
+	parameters, err := r.TemplateResolver.Resolve(ctx, object)
+	if err != nil {
+		log.Log.Error(err, fmt.Sprintf("Monitor templates of %s %s/%s not successfully resolved", kind, object.GetNamespace(), object.GetName()))
+		r.Recorder.Event(object, corev1.EventTypeWarning, MonitorSyncFailedReason, fmt.Sprintf("Monitor templates not successfully resolved: %s", err))
+		if err := patchStatusAnnotations(ctx, r.Client, object, monitorutil.StatusFailed, nil, ""); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, err
+	}
+	objectAnnotations := monitorutil.WithTemplateParameters(object.GetAnnotations(), parameters)
+
+	applied := isApplied(objectAnnotations, hosts, object.GetLabels())
+	paused := object.GetAnnotations()[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)] == monitorutil.StatusPaused
+	previousMonitors := recordedMonitors(object)
+	monitors := make(map[string]managedMonitor, len(hosts))
+	previewing := false
+	for _, target := range sortedKeys(hosts) {
+		hostWithScheme := monitorUrl(hosts[target], target, "")
+		annotations, err := r.buildHostAnnotations(object, kind, objectAnnotations, hosts[target], target, len(hosts))
+		if err != nil {
+			log.Log.Error(err, fmt.Sprintf("Monitor %s annotations not successfully rendered", hostWithScheme))
+			r.Recorder.Event(object, corev1.EventTypeWarning, MonitorSyncFailedReason, fmt.Sprintf("Monitor %s annotations not successfully rendered: %s", hostWithScheme, err))
+			return ctrl.Result{}, patchStatusAnnotations(ctx, r.Client, object, monitorutil.StatusFailed, nil, "")
+		}
+		hostWithScheme = monitorUrl(hosts[target], target, annotations[monitorutil.GetUptimeRobotMonitorPrefix()+monitorutil.PathAnnotation])
+		if applied {
+			drift, err := r.UtilProvider.GetMonitorDrift(ctx, apiKey, hostWithScheme, annotations)
+			if err == nil && !drift.HasDrift() {
+				monitors[hostWithScheme] = managedMonitor{Id: drift.MonitorId}
+				continue
+			}
+			if err != nil {
+				log.Log.Error(err, fmt.Sprintf("Monitor %s drift not successfully checked", hostWithScheme))
+			} else {
+				log.Log.Info(fmt.Sprintf("Monitor %s drifted: %s", hostWithScheme, drift))
+				r.Recorder.Event(object, corev1.EventTypeWarning, MonitorDriftedReason, fmt.Sprintf("Monitor %s drifted: %s", hostWithScheme, drift))
+			}
+		}
+		previous, recorded := previousMonitors[hostWithScheme]
+		if adoption := adoptionMode(annotations); !recorded && len(adoption) > 0 {
+			adoptId := annotations[monitorutil.GetUptimeRobotMonitorPrefix()+monitorutil.AdoptIdAnnotation]
+			drift, err := r.UtilProvider.FindMonitorToAdopt(ctx, apiKey, adoptId, hostWithScheme, annotations)
+			if err != nil {
+				log.Log.Error(err, fmt.Sprintf("Monitor %s to adopt not successfully found", hostWithScheme))
+				r.Recorder.Event(object, corev1.EventTypeWarning, MonitorSyncFailedReason, fmt.Sprintf("Monitor %s to adopt not successfully found: %s", hostWithScheme, err))
+				if err := patchStatusAnnotations(ctx, r.Client, object, monitorutil.StatusFailed, nil, ""); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{}, retryableSyncError(err)
+			}
+			if adoption == monitorutil.AdoptPreview {
+				previewing = true
+				report := fmt.Sprintf("No monitor of %s to adopt, a monitor would be created", hostWithScheme)
+				if !drift.Missing {
+					report = fmt.Sprintf("Adopting monitor %s for %s would change fields %v", drift.MonitorId, hostWithScheme, drift.Fields)
+				}
+				log.Log.Info(report)
+				r.Recorder.Event(object, corev1.EventTypeNormal, MonitorAdoptionPreviewReason, report)
+				continue
+			}
+			if drift.Missing {
+				log.Log.Info(fmt.Sprintf("No monitor of %s to adopt, a monitor is created", hostWithScheme))
+			} else {
+				report := fmt.Sprintf("Adopting monitor %s for %s, changing fields %v", drift.MonitorId, hostWithScheme, drift.Fields)
+				log.Log.Info(report)
+				r.Recorder.Event(object, corev1.EventTypeNormal, MonitorAdoptedReason, report)
+				previous, recorded = managedMonitor{Id: drift.MonitorId}, true
+			}
+		}
+		var monitorId string
+		if recorded {
+			// the monitor is edited in place so that renaming it keeps its history.
+			monitorId, err = r.UtilProvider.UpdateMonitor(ctx, apiKey, previous.Id, hostWithScheme, annotations)
+		} else {
+			monitorId, err = r.UtilProvider.CreateMonitor(ctx, apiKey, hostWithScheme, annotations)
+		}
+		if err != nil {
+			log.Log.Error(err, fmt.Sprintf("Monitor %s not successfully created/updated", hostWithScheme))
+			r.Recorder.Event(object, corev1.EventTypeWarning, MonitorSyncFailedReason, fmt.Sprintf("Monitor %s not successfully created/updated: %s", hostWithScheme, err))
+			if err := patchStatusAnnotations(ctx, r.Client, object, monitorutil.StatusFailed, nil, ""); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, retryableSyncError(err)
+		}
+		if paused || previousMonitors[hostWithScheme].Paused {
+			// the monitors were paused once the monitoring was disabled or the host removed, updating them does
+			// not resume them.
+			if err := r.UtilProvider.SetMonitorPausedById(ctx, apiKey, monitorId, false); err != nil {
+				log.Log.Error(err, fmt.Sprintf("Monitor %s not successfully resumed", hostWithScheme))
+				r.Recorder.Event(object, corev1.EventTypeWarning, MonitorSyncFailedReason, fmt.Sprintf("Monitor %s not successfully resumed: %s", hostWithScheme, err))
+				// the status stays paused so that resuming is retried with the next sync.
+				return ctrl.Result{}, retryableSyncError(err)
+			}
+		}
+		log.Log.Info(fmt.Sprintf("Monitor %s successfully created/updated", hostWithScheme))
+		r.Recorder.Event(object, corev1.EventTypeNormal, MonitorSyncedReason, fmt.Sprintf("Monitor %s successfully created/updated", hostWithScheme))
+		// monitors without an id, e.g. whose id was not found, are matched by their friendly name again. The recorded
+		// monitor of the host is kept meanwhile rather than released as the monitor of a removed host.
+		switch {
+		case len(monitorId) > 0:
+			monitors[hostWithScheme] = managedMonitor{Id: monitorId}
+		case recorded:
+			monitors[hostWithScheme] = previous
+		}
+	}
+
+	// the adoption is only previewed, the resource is synced once it is adopted.
+	if previewing {
+		return ctrl.Result{}, nil
+	}
+	if err := r.releaseRemovedMonitors(ctx, apiKey, object, previousMonitors, monitors); err != nil {
+		if err := patchStatusAnnotations(ctx, r.Client, object, monitorutil.StatusFailed, nil, ""); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, retryableSyncError(err)
+	}
+	if err := patchStatusAnnotations(ctx, r.Client, object, monitorutil.StatusSynced, monitors, desiredStateHash(objectAnnotations, hosts, object.GetLabels())); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.ResyncPeriod}, nil
+}
+
+// buildHostAnnotations returns the annotations of the monitor of target, a host optionally followed by a path, out
+// of the annotations of the resource merged with its templates, their templated values are rendered for the target.
+// When the resource has more than one target each monitor gets its own friendly name from FriendlyNameTemplate,
+// unless it is overridden for the host or templated.
+func (r *HostMonitorSyncer) buildHostAnnotations(object client.Object, kind string, objectAnnotations map[string]string, scheme string, target string, hostCount int) (map[string]string, error) {
+	host, path := monitorutil.SplitTarget(target)
+	hostAnnotations := monitorutil.GetHostAnnotations(objectAnnotations, host)
+	annotations, err := monitorutil.RenderAnnotations(hostAnnotations, monitorutil.AnnotationData{
+		Namespace: object.GetNamespace(),
+		Name:      object.GetName(),
+		Labels:    object.GetLabels(),
+		Host:      host,
+		Scheme:    scheme,
+		Path:      path,
+		ClusterId: r.ClusterId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	friendlyName := monitorutil.GetFriendlyName(annotations)
+	if len(friendlyName) == 0 {
+		return annotations, nil
+	}
+
+	if hostCount > 1 && !monitorutil.HasHostFriendlyName(objectAnnotations, host) && !monitorutil.IsTemplatedValue(monitorutil.GetFriendlyName(hostAnnotations)) {
+		tmpl := r.FriendlyNameTemplate
+		if tmpl == nil {
+			tmpl = template.Must(monitorutil.ParseFriendlyNameTemplate(monitorutil.DefaultFriendlyNameTemplate))
+		}
+		hostFriendlyName, err := monitorutil.RenderFriendlyName(tmpl, monitorutil.FriendlyNameData{
+			FriendlyName: friendlyName,
+			Host:         host,
+			Path:         path,
+			Name:         object.GetName(),
+			Namespace:    object.GetNamespace(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		friendlyName = hostFriendlyName
+	}
+	if len(r.ClusterId) > 0 {
+		friendlyName += monitorutil.GetOwnerMarker(r.ClusterId, monitorutil.Owner{Kind: kind, Namespace: object.GetNamespace(), Name: object.GetName()})
+	}
+	annotations[monitorutil.GetUptimeRobotMonitorPrefix()+httputil.FriendlyNameField] = friendlyName
+	return annotations, nil
+}
+
+// adoptionMode returns how the annotations of a monitor adopt an existing monitor, "true" or
+// monitorutil.AdoptPreview, it is empty when they do not. Setting the id to adopt implies "true".
+func adoptionMode(annotations map[string]string) string {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	switch adopt := annotations[prefix+monitorutil.AdoptAnnotation]; {
+	case adopt == "true" || adopt == monitorutil.AdoptPreview:
+		return adopt
+	case adopt != "false" && len(annotations[prefix+monitorutil.AdoptIdAnnotation]) > 0:
+		return "true"
+	}
+	return ""
+}
+
+// releaseRemovedMonitors applies the on-disable policy to the previous monitors of object whose host was removed,
+// paused monitors are added to monitors so that they are resumed should the host come back and deleted along with
+// object. Monitors still used by another host, e.g. sharing their friendly name, are left as they are.
+func (r *HostMonitorSyncer) releaseRemovedMonitors(ctx context.Context, apiKey string, object client.Object, previousMonitors map[string]managedMonitor, monitors map[string]managedMonitor) error {
+	currentIds := make(map[string]bool, len(monitors))
+	for _, monitor := range monitors {
+		currentIds[monitor.Id] = true
+	}
+	policy := r.onDisablePolicy(object)
+	for url, monitor := range previousMonitors {
+		if _, exists := monitors[url]; exists || currentIds[monitor.Id] {
+			continue
+		}
+		if policy == monitorutil.OnDisablePause {
+			if !monitor.Paused {
+				if err := r.UtilProvider.SetMonitorPausedById(ctx, apiKey, monitor.Id, true); err != nil {
+					log.Log.Error(err, fmt.Sprintf("Monitor %s of removed host not successfully paused", url))
+					r.Recorder.Event(object, corev1.EventTypeWarning, MonitorSyncFailedReason, fmt.Sprintf("Monitor %s of removed host not successfully paused: %s", url, err))
+					return err
+				}
+				log.Log.Info(fmt.Sprintf("Monitor %s of removed host successfully paused", url))
+				r.Recorder.Event(object, corev1.EventTypeNormal, MonitorPausedReason, fmt.Sprintf("Monitor %s of removed host successfully paused", url))
+			}
+			monitors[url] = managedMonitor{Id: monitor.Id, Paused: true}
+			continue
+		}
+		if err := r.UtilProvider.DeleteMonitorById(ctx, apiKey, monitor.Id); err != nil && !monitorutil.IsMonitorNotFound(err) {
+			log.Log.Error(err, fmt.Sprintf("Monitor %s of removed host not successfully deleted", url))
+			r.Recorder.Event(object, corev1.EventTypeWarning, MonitorDeleteFailedReason, fmt.Sprintf("Monitor %s of removed host not successfully deleted: %s", url, err))
+			return err
+		}
+		log.Log.Info(fmt.Sprintf("Monitor %s of removed host successfully deleted", url))
+		r.Recorder.Event(object, corev1.EventTypeNormal, MonitorDeletedReason, fmt.Sprintf("Monitor %s of removed host successfully deleted", url))
+	}
+	return nil
+}
+
+// disable applies the on-disable policy to the monitors of object whose monitoring was disabled. Paused monitors
+// keep the finalizer so that they are deleted along with object, deleted monitors release object entirely.
+func (r *HostMonitorSyncer) disable(ctx context.Context, object client.Object, kind string, hosts map[string]string) (ctrl.Result, error) {
+	apiKey, err := r.APIKeyResolver.Resolve(ctx, object)
+	if err != nil {
+		return r.releaseWithoutApiKey(ctx, object, kind, MonitorDisableFailedReason, err)
+	}
+
+	if r.onDisablePolicy(object) == monitorutil.OnDisablePause {
+		for _, id := range recordedMonitorIds(object) {
+			if err := r.UtilProvider.SetMonitorPausedById(ctx, apiKey, id, true); err != nil {
+				log.Log.Error(err, fmt.Sprintf("Monitor %s not successfully paused", id))
+				r.Recorder.Event(object, corev1.EventTypeWarning, MonitorDisableFailedReason, fmt.Sprintf("Monitor %s not successfully paused: %s", id, err))
+				if err := patchStatusAnnotations(ctx, r.Client, object, monitorutil.StatusFailed, nil, ""); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{}, retryableSyncError(err)
+			}
+			log.Log.Info(fmt.Sprintf("Monitor %s successfully paused", id))
+			r.Recorder.Event(object, corev1.EventTypeNormal, MonitorPausedReason, fmt.Sprintf("Monitor %s successfully paused", id))
+		}
+		return ctrl.Result{}, patchStatusAnnotations(ctx, r.Client, object, monitorutil.StatusPaused, nil, "")
+	}
+
+	if err := r.deleteMonitors(ctx, apiKey, object, kind, hosts); err != nil {
+		if err := patchStatusAnnotations(ctx, r.Client, object, monitorutil.StatusFailed, nil, ""); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, retryableSyncError(err)
+	}
+	return ctrl.Result{}, r.release(ctx, object)
+}
+
+// releaseWithoutApiKey releases object whose api key can not be resolved rather than retrying, the referenced Secret
+// is commonly deleted first along with its namespace. Its monitors are left on UptimeRobot.
+func (r *HostMonitorSyncer) releaseWithoutApiKey(ctx context.Context, object client.Object, kind string, reason string, err error) (ctrl.Result, error) {
+	log.Log.Error(err, fmt.Sprintf("Api key of %s %s/%s not successfully resolved, its monitors are left on UptimeRobot", kind, object.GetNamespace(), object.GetName()))
+	r.Recorder.Event(object, corev1.EventTypeWarning, reason, fmt.Sprintf("Api key not successfully resolved, the monitors are left on UptimeRobot: %s", err))
+	return ctrl.Result{}, r.release(ctx, object)
+}
+
+// release removes the sync status annotations and the finalizer of object, the operator no longer manages its
+// monitors.
+func (r *HostMonitorSyncer) release(ctx context.Context, object client.Object) error {
+	annotations := object.GetAnnotations()
+	for key := range annotations {
+		if monitorutil.IsStatusAnnotation(key) {
+			delete(annotations, key)
+		}
+	}
+	object.SetAnnotations(annotations)
+	controllerutil.RemoveFinalizer(object, monitorutil.GetUptimeRobotFinalizer())
+	return r.Update(ctx, object)
+}
+
+// onDisablePolicy returns the policy applied to the monitors of object once its monitoring is disabled, the
+// on-disable annotation takes precedence over OnDisable.
+func (r *HostMonitorSyncer) onDisablePolicy(object client.Object) string {
+	policy, exists := object.GetAnnotations()[monitorutil.GetUptimeRobotMonitorPrefix()+monitorutil.OnDisableAnnotation]
+	if !exists {
+		policy = r.OnDisable
+	}
+	if policy == monitorutil.OnDisablePause {
+		return monitorutil.OnDisablePause
+	}
+	return monitorutil.OnDisableDelete
+}
+
+// deleteMonitors deletes the monitors of object. They are deleted by their recorded ids once recorded by the
+// managed-monitors annotation or once its monitoring is disabled since its annotations may no longer describe them,
+// otherwise by the annotations of each host.
+func (r *HostMonitorSyncer) deleteMonitors(ctx context.Context, apiKey string, object client.Object, kind string, hosts map[string]string) error {
+	_, tracked := object.GetAnnotations()[monitorutil.GetStatusAnnotationKey(monitorutil.ManagedMonitorsAnnotation)]
+	if ids := recordedMonitorIds(object); len(ids) > 0 && (tracked || !r.isEnabled(object)) {
+		for _, id := range ids {
+			if err := r.UtilProvider.DeleteMonitorById(ctx, apiKey, id); err != nil && !monitorutil.IsMonitorNotFound(err) {
+				log.Log.Error(err, fmt.Sprintf("Monitor %s not successfully deleted", id))
+				r.Recorder.Event(object, corev1.EventTypeWarning, MonitorDeleteFailedReason, fmt.Sprintf("Monitor %s not successfully deleted: %s", id, err))
+				return err
+			}
+			log.Log.Info(fmt.Sprintf("Monitor %s successfully deleted", id))
+			r.Recorder.Event(object, corev1.EventTypeNormal, MonitorDeletedReason, fmt.Sprintf("Monitor %s successfully deleted", id))
+		}
+		return nil
+	}
+	parameters, err := r.TemplateResolver.Resolve(ctx, object)
+	if err != nil {
+		log.Log.Error(err, fmt.Sprintf("Monitor templates of %s %s/%s not successfully resolved", kind, object.GetNamespace(), object.GetName()))
+		r.Recorder.Event(object, corev1.EventTypeWarning, MonitorDeleteFailedReason, fmt.Sprintf("Monitor templates not successfully resolved: %s", err))
+		return err
+	}
+	return r.cleanUpAfterDeletion(ctx, apiKey, object, kind, hosts, monitorutil.WithTemplateParameters(object.GetAnnotations(), parameters))
+}
+
+// cleanUpAfterDeletion deletes the monitor of every host of the resource, monitors that no longer exist
+// are considered deleted.
+func (r *HostMonitorSyncer) cleanUpAfterDeletion(ctx context.Context, apiKey string, object client.Object, kind string, hosts map[string]string, objectAnnotations map[string]string) error {
+	for _, target := range sortedKeys(hosts) {
+		hostWithScheme := monitorUrl(hosts[target], target, "")
+		annotations, err := r.buildHostAnnotations(object, kind, objectAnnotations, hosts[target], target, len(hosts))
+		if err != nil {
+			log.Log.Error(err, fmt.Sprintf("Monitor %s annotations not successfully rendered", hostWithScheme))
+			r.Recorder.Event(object, corev1.EventTypeWarning, MonitorDeleteFailedReason, fmt.Sprintf("Monitor %s annotations not successfully rendered: %s", hostWithScheme, err))
+			return err
+		}
+		hostWithScheme = monitorUrl(hosts[target], target, annotations[monitorutil.GetUptimeRobotMonitorPrefix()+monitorutil.PathAnnotation])
+		friendlyName := monitorutil.GetFriendlyName(annotations)
+		err = r.UtilProvider.DeleteMonitor(ctx, apiKey, hostWithScheme, annotations)
+		if err != nil && !monitorutil.IsMonitorNotFound(err) {
+			log.Log.Error(err, fmt.Sprintf("Monitor %s not successfully deleted", friendlyName))
+			r.Recorder.Event(object, corev1.EventTypeWarning, MonitorDeleteFailedReason, fmt.Sprintf("Monitor %s not successfully deleted: %s", friendlyName, err))
+			return err
+		}
+		log.Log.Info(fmt.Sprintf("Monitor %s successfully deleted", friendlyName))
+		r.Recorder.Event(object, corev1.EventTypeNormal, MonitorDeletedReason, fmt.Sprintf("Monitor %s successfully deleted", friendlyName))
+	}
+	return nil
+}
+
+// WatchDependencies makes the controller watch what the monitors of its resources depend on besides the resources:
+// the monitor templates when TemplateResolver is set and the labels of the namespaces when NamespaceSelector is set.
+// newList creates an empty list of the resources of the controller.
+func (r *HostMonitorSyncer) WatchDependencies(b *builder.Builder, newList func() client.ObjectList) *builder.Builder {
+	if r.TemplateResolver != nil {
+		b = b.Watches(&source.Kind{Type: &monitoringv1alpha1.MonitorTemplate{}}, r.EnqueueTemplateUsers(newList)).
+			Watches(&source.Kind{Type: &monitoringv1alpha1.ClusterMonitorTemplate{}}, r.EnqueueTemplateUsers(newList))
+	}
+	if r.NamespaceSelector != nil {
+		b = b.Watches(&source.Kind{Type: &corev1.Namespace{}}, r.enqueueNamespaceResources(newList), builder.WithPredicates(predicate.LabelChangedPredicate{}))
+	}
+	return b
+}
+
+// enqueueNamespaceResources returns the handler enqueuing the managed resources of a namespace whose labels changed,
+// so that the resources of a namespace are synced once it matches NamespaceSelector.
+func (r *HostMonitorSyncer) enqueueNamespaceResources(newList func() client.ObjectList) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(namespace client.Object) []reconcile.Request {
+		list := newList()
+		if err := r.List(context.Background(), list, client.InNamespace(namespace.GetName())); err != nil {
+			log.Log.Error(err, fmt.Sprintf("Resources of namespace %s not successfully listed", namespace.GetName()))
+			return nil
+		}
+		objects, err := meta.ExtractList(list)
+		if err != nil {
+			log.Log.Error(err, fmt.Sprintf("Resources of namespace %s not successfully listed", namespace.GetName()))
+			return nil
+		}
+		requests := make([]reconcile.Request, 0)
+		for _, item := range objects {
+			if object, ok := item.(client.Object); ok && r.isManaged(object) {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: object.GetNamespace(), Name: object.GetName()}})
+			}
+		}
+		return requests
+	})
+}
+
+// FilterEnabled only lets through the events of resources with an enabled monitor within the label and namespace
+// selectors, or pending clean up after their deletion or the disabling of their monitor.
+func (r *HostMonitorSyncer) FilterEnabled() predicate.Predicate {
+	return predicate.Funcs{CreateFunc: func(event event.CreateEvent) bool {
+		return r.filterCreateEvent(event)
+	}, UpdateFunc: func(updateEvent event.UpdateEvent) bool {
+		return r.filterUpdateEvent(updateEvent)
+	}, DeleteFunc: func(deleteEvent event.DeleteEvent) bool {
+		return r.filterDeleteEvent(deleteEvent)
+	}, GenericFunc: func(genericEvent event.GenericEvent) bool {
+		return r.filterGenericEvent(genericEvent)
+	}}
+}
+
+func (r *HostMonitorSyncer) filterGenericEvent(genericEvent event.GenericEvent) bool {
+	if genericEvent.Object != nil {
+		return r.isManaged(genericEvent.Object) || r.hasPendingDisable(genericEvent.Object)
+	}
+	return false
+}
+
+// filterDeleteEvent drops delete events, monitors are removed while the finalizer holds the resource.
+func (r *HostMonitorSyncer) filterDeleteEvent(deleteEvent event.DeleteEvent) bool {
+	return false
+}
+
+func (r *HostMonitorSyncer) filterUpdateEvent(updateEvent event.UpdateEvent) bool {
+	if updateEvent.ObjectNew != nil {
+		if isPendingCleanUp(updateEvent.ObjectNew) {
+			return true
+		}
+		if hasOnlyStatusChanges(updateEvent.ObjectOld, updateEvent.ObjectNew) {
+			return false
+		}
+		return r.isManaged(updateEvent.ObjectNew) || r.isDisabled(updateEvent.ObjectOld, updateEvent.ObjectNew)
+	}
+	return false
+}
+
+func (r *HostMonitorSyncer) filterCreateEvent(event event.CreateEvent) bool {
+	if event.Object != nil {
+		// resources disabled while the operator was down are only seen when their events are replayed.
+		return r.isManaged(event.Object) || r.hasPendingDisable(event.Object)
+	}
+	return false
+}
+
+// monitorUrl returns the url of the monitor of target, a host optionally followed by a path which takes precedence
+// over path. Targets without a scheme are monitored by their bare address.
+func monitorUrl(scheme string, target string, path string) string {
+	if len(scheme) == 0 {
+		return target
+	}
+	host, targetPath := monitorutil.SplitTarget(target)
+	if len(targetPath) > 0 {
+		path = targetPath
+	}
+	if len(path) > 0 && !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return scheme + "://" + host + path
+}
+
+// isPendingCleanUp reports whether the object is being deleted and still holds the operator's finalizer.
+func isPendingCleanUp(object client.Object) bool {
+	return !object.GetDeletionTimestamp().IsZero() && controllerutil.ContainsFinalizer(object, monitorutil.GetUptimeRobotFinalizer())
+}
+
+// isDisabled reports whether the update disabled the monitors of an object whose monitors are still to be deleted or
+// paused.
+func (r *HostMonitorSyncer) isDisabled(oldObject client.Object, newObject client.Object) bool {
+	return oldObject != nil && r.isEnabled(oldObject) && r.hasPendingDisable(newObject)
+}
+
+// hasPendingDisable reports whether the monitors of object within the scope of the operator were disabled but not
+// yet deleted nor paused.
+func (r *HostMonitorSyncer) hasPendingDisable(object client.Object) bool {
+	return object.GetDeletionTimestamp().IsZero() && controllerutil.ContainsFinalizer(object, monitorutil.GetUptimeRobotFinalizer()) &&
+		object.GetAnnotations()[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)] != monitorutil.StatusPaused &&
+		!r.isEnabled(object) && r.inScope(object)
+}
+
+// recordedMonitorIds returns the ids of the monitors of object recorded by its last successful sync, paused ones
+// included.
+func recordedMonitorIds(object client.Object) []string {
+	monitors := recordedMonitors(object)
+	ids := make([]string, 0, len(monitors))
+	seen := make(map[string]bool, len(monitors))
+	for _, monitor := range monitors {
+		if !seen[monitor.Id] {
+			seen[monitor.Id] = true
+			ids = append(ids, monitor.Id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// isManaged reports whether the monitors of object are enabled and within the scope of the operator.
+func (r *HostMonitorSyncer) isManaged(object client.Object) bool {
+	return r.isEnabled(object) && r.inScope(object)
+}
+
+// isEnabled reports whether the monitors of object are enabled by the enable annotation or its ingress class.
+func (r *HostMonitorSyncer) isEnabled(object client.Object) bool {
+	if r.hasEnabledUptimeRobotMonitor(object.GetAnnotations()) {
+		return true
+	}
+	ingress, ok := object.(*network.Ingress)
+	if !ok || r.IngressClassFilter == nil {
+		return false
+	}
+	_, annotated := ingress.Annotations[monitorutil.GetUptimeRobotDomain()]
+	return !annotated && r.IngressClassFilter.AutoEnables(ingress)
+}
+
+// inScope reports whether object is within the ingress classes, label and namespace selectors of the operator.
+func (r *HostMonitorSyncer) inScope(object client.Object) bool {
+	if ingress, ok := object.(*network.Ingress); ok && r.IngressClassFilter != nil && !r.IngressClassFilter.Admits(ingress) {
+		return false
+	}
+	if r.LabelSelector != nil && !r.LabelSelector.Matches(labels.Set(object.GetLabels())) {
+		return false
+	}
+	if r.NamespaceSelector == nil {
+		return true
+	}
+	namespace := &corev1.Namespace{}
+	if err := r.Get(context.Background(), types.NamespacedName{Name: object.GetNamespace()}, namespace); err != nil {
+		if !errors.IsNotFound(err) {
+			log.Log.Error(err, fmt.Sprintf("Namespace %s not successfully fetched", object.GetNamespace()))
+		}
+		return false
+	}
+	return r.NamespaceSelector.Matches(labels.Set(namespace.Labels))
+}
+
+func (r *HostMonitorSyncer) hasEnabledUptimeRobotMonitor(annotationMap map[string]string) bool {
+	if val, exists := annotationMap[monitorutil.GetUptimeRobotDomain()]; exists {
+		isEnabled, err := strconv.ParseBool(val)
+		if err != nil {
+			return false
+		}
+		return isEnabled
+	}
+	return false
+}
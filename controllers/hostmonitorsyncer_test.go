@@ -0,0 +1,397 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	network "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestHostMonitorSyncer_buildHostAnnotations(t *testing.T) {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	ingress := &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{
+		monitorutil.GetUptimeRobotDomain():                   "true",
+		prefix + httputil.FriendlyNameField:                  "tester",
+		prefix + httputil.FriendlyNameField + ".b.localhost": "custom",
+	}}}
+	tmpl, err := monitorutil.ParseFriendlyNameTemplate("{{.Namespace}}-{{.Host}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		syncer    *HostMonitorSyncer
+		host      string
+		hostCount int
+		want      string
+	}{
+		{name: "should keep friendly name of single host", syncer: &HostMonitorSyncer{}, host: "a.localhost", hostCount: 1, want: "tester"},
+		{name: "should render default template for multiple hosts", syncer: &HostMonitorSyncer{}, host: "a.localhost", hostCount: 2, want: "tester-a.localhost"},
+		{name: "should render configured template", syncer: &HostMonitorSyncer{FriendlyNameTemplate: tmpl}, host: "a.localhost", hostCount: 2, want: "default-a.localhost"},
+		{name: "should use friendly name overridden for host", syncer: &HostMonitorSyncer{}, host: "b.localhost", hostCount: 2, want: "custom"},
+		{name: "should render default template for multiple paths", syncer: &HostMonitorSyncer{}, host: "a.localhost/healthz", hostCount: 2, want: "tester-a.localhost/healthz"},
+		{name: "should mark friendly name with owner", syncer: &HostMonitorSyncer{ClusterId: "prod"}, host: "a.localhost", hostCount: 1, want: "tester [prod/Ingress/default/web]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.syncer.buildHostAnnotations(ingress, IngressKind, ingress.Annotations, "http", tt.host, tt.hostCount)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got[prefix+httputil.FriendlyNameField] != tt.want {
+				t.Errorf("friendly name = %v, want %v", got[prefix+httputil.FriendlyNameField], tt.want)
+			}
+		})
+	}
+}
+
+func TestHostMonitorSyncer_buildHostAnnotations_rendered(t *testing.T) {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	ingress := &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "web", Namespace: "default", Labels: map[string]string{"team": "a"}, Annotations: map[string]string{
+		monitorutil.GetUptimeRobotDomain():  "true",
+		prefix + httputil.FriendlyNameField: "{{ .Namespace }}/{{ .Name }} {{ .Host }}",
+		prefix + monitorutil.Url:            "{{ .Scheme }}://{{ .Host }}/healthz",
+		prefix + httputil.KeywordValueField: "{{ .Labels.team }} {{ .ClusterId }}",
+	}}}
+	r := &HostMonitorSyncer{ClusterId: "prod"}
+	got, err := r.buildHostAnnotations(ingress, IngressKind, ingress.Annotations, "https", "a.localhost", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		prefix + httputil.FriendlyNameField: "default/web a.localhost [prod/Ingress/default/web]",
+		prefix + monitorutil.Url:            "https://a.localhost/healthz",
+		prefix + httputil.KeywordValueField: "a prod",
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %v, want %v", key, got[key], value)
+		}
+	}
+
+	ingress.Annotations[prefix+httputil.KeywordValueField] = "{{ .Labels.missing }}"
+	if _, err := r.buildHostAnnotations(ingress, IngressKind, ingress.Annotations, "https", "a.localhost", 2); err == nil {
+		t.Errorf("buildHostAnnotations() error = nil, want error on missing label")
+	}
+}
+
+func Test_monitorUrl(t *testing.T) {
+	tests := []struct {
+		name   string
+		scheme string
+		target string
+		path   string
+		want   string
+	}{
+		{name: "should join scheme and host", scheme: "https", target: "a.localhost", want: "https://a.localhost"},
+		{name: "should append path", scheme: "https", target: "a.localhost", path: "/healthz", want: "https://a.localhost/healthz"},
+		{name: "should prefix path with slash", scheme: "http", target: "a.localhost", path: "healthz", want: "http://a.localhost/healthz"},
+		{name: "should prefer path of target", scheme: "http", target: "a.localhost/api/healthz", path: "/healthz", want: "http://a.localhost/api/healthz"},
+		{name: "should return bare address without scheme", target: "10.0.0.1", path: "/healthz", want: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := monitorUrl(tt.scheme, tt.target, tt.path); got != tt.want {
+				t.Errorf("monitorUrl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostMonitorSyncer_Sync_disabled(t *testing.T) {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	statusKey := monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)
+	tests := []struct {
+		name          string
+		onDisable     string
+		annotations   map[string]string
+		setupMocks    func(testutilprovider *testUtilProvider)
+		wantStatus    string
+		wantFinalizer bool
+	}{
+		{name: "should delete the recorded monitors by default", annotations: map[string]string{
+			monitorutil.GetUptimeRobotDomain(): "false",
+			statusKey:                          monitorutil.StatusSynced,
+		}, setupMocks: func(testutilprovider *testUtilProvider) {
+			testutilprovider.On("DeleteMonitorById", "", "1").Return(nil)
+			testutilprovider.On("DeleteMonitorById", "", "2").Return(nil)
+		}, wantStatus: "", wantFinalizer: false},
+		{name: "should pause the recorded monitors with the on-disable annotation", onDisable: monitorutil.OnDisableDelete, annotations: map[string]string{
+			statusKey:                                monitorutil.StatusSynced,
+			prefix + monitorutil.OnDisableAnnotation: monitorutil.OnDisablePause,
+		}, setupMocks: func(testutilprovider *testUtilProvider) {
+			testutilprovider.On("SetMonitorPausedById", "", "1", true).Return(nil)
+			testutilprovider.On("SetMonitorPausedById", "", "2", true).Return(nil)
+		}, wantStatus: monitorutil.StatusPaused, wantFinalizer: true},
+		{name: "should pause the recorded monitors with the on-disable policy", onDisable: monitorutil.OnDisablePause, annotations: map[string]string{
+			monitorutil.GetUptimeRobotDomain(): "false",
+			statusKey:                          monitorutil.StatusSynced,
+		}, setupMocks: func(testutilprovider *testUtilProvider) {
+			testutilprovider.On("SetMonitorPausedById", "", "1", true).Return(nil)
+			testutilprovider.On("SetMonitorPausedById", "", "2", true).Return(nil)
+		}, wantStatus: monitorutil.StatusPaused, wantFinalizer: true},
+		{name: "should resume the paused monitors once enabled again", onDisable: monitorutil.OnDisablePause, annotations: map[string]string{
+			monitorutil.GetUptimeRobotDomain():  "true",
+			prefix + httputil.FriendlyNameField: "tester",
+			statusKey:                           monitorutil.StatusPaused,
+		}, setupMocks: func(testutilprovider *testUtilProvider) {
+			testutilprovider.On("CreateMonitor", "", "http://a.local", mock.Anything).Return("1", nil)
+			testutilprovider.On("SetMonitorPausedById", "", "1", false).Return(nil)
+			// the host of monitor 2 is gone.
+			testutilprovider.On("SetMonitorPausedById", "", "2", true).Return(nil)
+		}, wantStatus: monitorutil.StatusSynced, wantFinalizer: true},
+		{name: "should leave paused monitors as they are", onDisable: monitorutil.OnDisablePause, annotations: map[string]string{
+			monitorutil.GetUptimeRobotDomain(): "false",
+			statusKey:                          monitorutil.StatusPaused,
+		}, setupMocks: func(testutilprovider *testUtilProvider) {}, wantStatus: monitorutil.StatusPaused, wantFinalizer: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation): "1,2"}
+			for key, value := range tt.annotations {
+				annotations[key] = value
+			}
+			ingress := &network.Ingress{ObjectMeta: ctrl.ObjectMeta{
+				Name:        "ingress",
+				Namespace:   "default",
+				Finalizers:  []string{monitorutil.GetUptimeRobotFinalizer()},
+				Annotations: annotations,
+			}}
+			c := fake.NewClientBuilder().WithScheme(newTemplateScheme(t)).WithObjects(ingress).Build()
+			testutilprovider := &testUtilProvider{}
+			tt.setupMocks(testutilprovider)
+			r := &HostMonitorSyncer{Client: c, Recorder: record.NewFakeRecorder(100), OnDisable: tt.onDisable, UtilProvider: testutilprovider}
+
+			if _, err := r.Sync(context.Background(), ingress, IngressKind, map[string]string{"a.local": "http"}); err != nil {
+				t.Fatal(err)
+			}
+			testutilprovider.AssertExpectations(t)
+
+			got := &network.Ingress{}
+			if err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "ingress"}, got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Annotations[statusKey] != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got.Annotations[statusKey], tt.wantStatus)
+			}
+			if controllerutil.ContainsFinalizer(got, monitorutil.GetUptimeRobotFinalizer()) != tt.wantFinalizer {
+				t.Errorf("finalizer = %v, want %v", !tt.wantFinalizer, tt.wantFinalizer)
+			}
+		})
+	}
+}
+
+func TestHostMonitorSyncer_Sync_removedHosts(t *testing.T) {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	tests := []struct {
+		name                string
+		onDisable           string
+		updatedId           string
+		setupMocks          func(testutilprovider *testUtilProvider)
+		wantIds             string
+		wantManagedMonitors string
+	}{
+		{name: "should delete the monitors of removed hosts", updatedId: "1", setupMocks: func(testutilprovider *testUtilProvider) {
+			testutilprovider.On("DeleteMonitorById", "", "2").Return(nil)
+		}, wantIds: "1", wantManagedMonitors: `{"http://a.local":{"id":"1"}}`},
+		{name: "should pause the monitors of removed hosts with the on-disable policy", onDisable: monitorutil.OnDisablePause, updatedId: "1", setupMocks: func(testutilprovider *testUtilProvider) {
+			testutilprovider.On("SetMonitorPausedById", "", "2", true).Return(nil)
+		}, wantIds: "1", wantManagedMonitors: `{"http://a.local":{"id":"1"},"http://b.local":{"id":"2","paused":true}}`},
+		{name: "should keep the recorded monitor of a host updated without id", setupMocks: func(testutilprovider *testUtilProvider) {
+			testutilprovider.On("DeleteMonitorById", "", "2").Return(nil)
+		}, wantIds: "1", wantManagedMonitors: `{"http://a.local":{"id":"1"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress := &network.Ingress{ObjectMeta: ctrl.ObjectMeta{
+				Name:       "ingress",
+				Namespace:  "default",
+				Finalizers: []string{monitorutil.GetUptimeRobotFinalizer()},
+				Annotations: map[string]string{
+					monitorutil.GetUptimeRobotDomain():                                        "true",
+					prefix + httputil.FriendlyNameField:                                       "tester",
+					monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation):          monitorutil.StatusSynced,
+					monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation):              "1,2",
+					monitorutil.GetStatusAnnotationKey(monitorutil.ManagedMonitorsAnnotation): `{"http://a.local":{"id":"1"},"http://b.local":{"id":"2"}}`,
+				},
+			}}
+			c := fake.NewClientBuilder().WithScheme(newTemplateScheme(t)).WithObjects(ingress).Build()
+			testutilprovider := &testUtilProvider{}
+			testutilprovider.On("UpdateMonitor", "", "1", "http://a.local", mock.Anything).Return(tt.updatedId, nil)
+			tt.setupMocks(testutilprovider)
+			r := &HostMonitorSyncer{Client: c, Recorder: record.NewFakeRecorder(100), OnDisable: tt.onDisable, UtilProvider: testutilprovider}
+
+			if _, err := r.Sync(context.Background(), ingress, IngressKind, map[string]string{"a.local": "http"}); err != nil {
+				t.Fatal(err)
+			}
+			testutilprovider.AssertExpectations(t)
+
+			got := &network.Ingress{}
+			if err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "ingress"}, got); err != nil {
+				t.Fatal(err)
+			}
+			if ids := got.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)]; ids != tt.wantIds {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIds)
+			}
+			if managedMonitors := got.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.ManagedMonitorsAnnotation)]; managedMonitors != tt.wantManagedMonitors {
+				t.Errorf("managed monitors = %v, want %v", managedMonitors, tt.wantManagedMonitors)
+			}
+		})
+	}
+}
+
+func TestHostMonitorSyncer_Sync_adopt(t *testing.T) {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	statusKey := monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)
+	managedMonitorsKey := monitorutil.GetStatusAnnotationKey(monitorutil.ManagedMonitorsAnnotation)
+	tests := []struct {
+		name                string
+		annotations         map[string]string
+		setupMocks          func(testutilprovider *testUtilProvider)
+		wantErr             bool
+		wantStatus          string
+		wantManagedMonitors string
+	}{
+		{name: "should adopt monitor found by its url or friendly name", annotations: map[string]string{
+			prefix + monitorutil.AdoptAnnotation: "true",
+		}, setupMocks: func(testutilprovider *testUtilProvider) {
+			testutilprovider.On("FindMonitorToAdopt", "", "", "http://a.local", mock.Anything).Return(&monitorutil.Drift{MonitorId: "7", Fields: []string{monitorutil.Interval}}, nil)
+			testutilprovider.On("UpdateMonitor", "", "7", "http://a.local", mock.Anything).Return("7", nil)
+		}, wantStatus: monitorutil.StatusSynced, wantManagedMonitors: `{"http://a.local":{"id":"7"}}`},
+		{name: "should adopt monitor with the id", annotations: map[string]string{
+			prefix + monitorutil.AdoptIdAnnotation: "7",
+		}, setupMocks: func(testutilprovider *testUtilProvider) {
+			testutilprovider.On("FindMonitorToAdopt", "", "7", "http://a.local", mock.Anything).Return(&monitorutil.Drift{MonitorId: "7", Fields: []string{}}, nil)
+			testutilprovider.On("UpdateMonitor", "", "7", "http://a.local", mock.Anything).Return("7", nil)
+		}, wantStatus: monitorutil.StatusSynced, wantManagedMonitors: `{"http://a.local":{"id":"7"}}`},
+		{name: "should create monitor when there is none to adopt", annotations: map[string]string{
+			prefix + monitorutil.AdoptAnnotation: "true",
+		}, setupMocks: func(testutilprovider *testUtilProvider) {
+			testutilprovider.On("FindMonitorToAdopt", "", "", "http://a.local", mock.Anything).Return(&monitorutil.Drift{Missing: true}, nil)
+			testutilprovider.On("CreateMonitor", "", "http://a.local", mock.Anything).Return("8", nil)
+		}, wantStatus: monitorutil.StatusSynced, wantManagedMonitors: `{"http://a.local":{"id":"8"}}`},
+		{name: "should only report the changes of the adoption preview", annotations: map[string]string{
+			prefix + monitorutil.AdoptAnnotation: monitorutil.AdoptPreview,
+		}, setupMocks: func(testutilprovider *testUtilProvider) {
+			testutilprovider.On("FindMonitorToAdopt", "", "", "http://a.local", mock.Anything).Return(&monitorutil.Drift{MonitorId: "7", Fields: []string{monitorutil.Interval}}, nil)
+		}, wantStatus: "", wantManagedMonitors: ""},
+		{name: "should return err when monitor with the id is not found", annotations: map[string]string{
+			prefix + monitorutil.AdoptIdAnnotation: "7",
+		}, setupMocks: func(testutilprovider *testUtilProvider) {
+			testutilprovider.On("FindMonitorToAdopt", "", "7", "http://a.local", mock.Anything).Return(nil, &monitorutil.APIError{StatusCode: 503})
+		}, wantErr: true, wantStatus: monitorutil.StatusFailed, wantManagedMonitors: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := map[string]string{
+				monitorutil.GetUptimeRobotDomain():  "true",
+				prefix + httputil.FriendlyNameField: "tester",
+			}
+			for key, value := range tt.annotations {
+				annotations[key] = value
+			}
+			ingress := &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "ingress", Namespace: "default", Annotations: annotations}}
+			c := fake.NewClientBuilder().WithScheme(newTemplateScheme(t)).WithObjects(ingress).Build()
+			recorder := record.NewFakeRecorder(100)
+			testutilprovider := &testUtilProvider{}
+			tt.setupMocks(testutilprovider)
+			r := &HostMonitorSyncer{Client: c, Recorder: recorder, UtilProvider: testutilprovider}
+
+			if _, err := r.Sync(context.Background(), ingress, IngressKind, map[string]string{"a.local": "http"}); (err != nil) != tt.wantErr {
+				t.Errorf("Sync() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			testutilprovider.AssertExpectations(t)
+
+			got := &network.Ingress{}
+			if err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "ingress"}, got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Annotations[statusKey] != tt.wantStatus {
+				t.Errorf("status = %v, want %v", got.Annotations[statusKey], tt.wantStatus)
+			}
+			if got.Annotations[managedMonitorsKey] != tt.wantManagedMonitors {
+				t.Errorf("managed monitors = %v, want %v", got.Annotations[managedMonitorsKey], tt.wantManagedMonitors)
+			}
+		})
+	}
+}
+
+func TestHostMonitorSyncer_Sync_deletedApiKeySecret(t *testing.T) {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	tests := []struct {
+		name     string
+		enabled  string
+		deleting bool
+	}{
+		{name: "should release deleted ingresses", enabled: "true", deleting: true},
+		{name: "should release disabled ingresses", enabled: "false"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingress := &network.Ingress{ObjectMeta: ctrl.ObjectMeta{
+				Name:       "ingress",
+				Namespace:  "default",
+				Finalizers: []string{monitorutil.GetUptimeRobotFinalizer()},
+				Annotations: map[string]string{
+					monitorutil.GetUptimeRobotDomain():                               tt.enabled,
+					prefix + monitorutil.ApiKeySecretAnnotation:                      "uptimerobot/api-key",
+					monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation): monitorutil.StatusSynced,
+					monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation):     "1",
+				},
+			}}
+			if tt.deleting {
+				now := metav1.Now()
+				ingress.DeletionTimestamp = &now
+			}
+			c := fake.NewClientBuilder().WithScheme(newTemplateScheme(t)).WithObjects(ingress).Build()
+			testutilprovider := &testUtilProvider{}
+			recorder := record.NewFakeRecorder(10)
+			r := &HostMonitorSyncer{Client: c, Recorder: recorder, UtilProvider: testutilprovider,
+				APIKeyResolver: &APIKeyResolver{Client: c, SecretReader: c}}
+
+			if _, err := r.Sync(context.Background(), ingress, IngressKind, map[string]string{"a.local": "http"}); err != nil {
+				t.Fatal(err)
+			}
+			testutilprovider.AssertNotCalled(t, "DeleteMonitorById", mock.Anything, mock.Anything)
+			got := &network.Ingress{}
+			err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "ingress"}, got)
+			if tt.deleting && !apierrors.IsNotFound(err) {
+				t.Errorf("ingress = %v, %v, want it deleted once its finalizer is removed", got, err)
+			}
+			if !tt.deleting && (err != nil || len(got.Finalizers) > 0 || len(got.Annotations) != 2) {
+				t.Errorf("ingress = %v, %v, want it released", got, err)
+			}
+			if event := <-recorder.Events; !strings.Contains(event, corev1.EventTypeWarning) {
+				t.Errorf("event = %v, want a warning", event)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+// HTTPRouteReconciler reconciles the monitors of Gateway API HTTPRoute objects, a monitor is created for each
+// hostname of the route.
+type HTTPRouteReconciler struct {
+	HostMonitorSyncer
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;watch;list;update;patch
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways,verbs=get;watch;list
+
+func (r *HTTPRouteReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	route := &gatewayv1beta1.HTTPRoute{}
+	if err := r.Get(ctx, req.NamespacedName, route); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	return r.Sync(ctx, route, HTTPRouteKind, r.buildRouteHostSchemeMap(ctx, route))
+}
+
+// buildRouteHostSchemeMap maps the hostnames of the route to https when a HTTPS listener of a parent gateway
+// accepts them, analogous to the TLS hosts of an ingress. Wildcard hostnames can not be monitored and are skipped.
+// Gateways that are gone or can not be read, e.g. outside of the watched namespaces, accept no hostnames so that
+// they neither block the sync nor the deletion of the route.
+func (r *HTTPRouteReconciler) buildRouteHostSchemeMap(ctx context.Context, route *gatewayv1beta1.HTTPRoute) map[string]string {
+	hosts := map[string]string{}
+	for _, hostname := range route.Spec.Hostnames {
+		if !strings.HasPrefix(string(hostname), "*") {
+			hosts[string(hostname)] = "http"
+		}
+	}
+
+	for _, parentRef := range route.Spec.ParentRefs {
+		if !isGatewayParentRef(parentRef) {
+			continue
+		}
+		gateway := &gatewayv1beta1.Gateway{}
+		gatewayName := types.NamespacedName{Namespace: parentNamespace(route, parentRef), Name: string(parentRef.Name)}
+		if err := r.Get(ctx, gatewayName, gateway); err != nil {
+			if !errors.IsNotFound(err) {
+				log.Log.Error(err, fmt.Sprintf("Gateway %s of HTTPRoute %s/%s not successfully read", gatewayName, route.Namespace, route.Name))
+				r.Recorder.Event(route, corev1.EventTypeWarning, MonitorSyncFailedReason, fmt.Sprintf("Gateway %s not successfully read, its listeners are ignored: %s", gatewayName, err))
+			}
+			continue
+		}
+		for _, listener := range gateway.Spec.Listeners {
+			if listener.Protocol != gatewayv1beta1.HTTPSProtocolType ||
+				(parentRef.SectionName != nil && *parentRef.SectionName != listener.Name) ||
+				(parentRef.Port != nil && *parentRef.Port != listener.Port) {
+				continue
+			}
+			for host := range hosts {
+				if listener.Hostname == nil || hostnameMatches(string(*listener.Hostname), host) {
+					hosts[host] = "https"
+				}
+			}
+		}
+	}
+	return hosts
+}
+
+func isGatewayParentRef(parentRef gatewayv1beta1.ParentReference) bool {
+	return (parentRef.Group == nil || *parentRef.Group == gatewayv1beta1.GroupName) &&
+		(parentRef.Kind == nil || *parentRef.Kind == "Gateway")
+}
+
+func parentNamespace(route *gatewayv1beta1.HTTPRoute, parentRef gatewayv1beta1.ParentReference) string {
+	if parentRef.Namespace != nil {
+		return string(*parentRef.Namespace)
+	}
+	return route.Namespace
+}
+
+// hostnameMatches reports whether host is matched by the listener hostname, which may be prefixed with a
+// wildcard label e.g. *.example.com.
+func hostnameMatches(listenerHostname string, host string) bool {
+	if strings.HasPrefix(listenerHostname, "*.") {
+		return strings.HasSuffix(host, listenerHostname[1:])
+	}
+	return listenerHostname == host
+}
+
+// findRoutesForGateway enqueues the enabled routes attached to the gateway so that their schemes follow the
+// listeners of the gateway.
+func (r *HTTPRouteReconciler) findRoutesForGateway(gateway client.Object) []reconcile.Request {
+	routes := &gatewayv1beta1.HTTPRouteList{}
+	if err := r.List(context.Background(), routes); err != nil {
+		log.Log.Error(err, fmt.Sprintf("HTTPRoutes of gateway %s/%s not successfully listed", gateway.GetNamespace(), gateway.GetName()))
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for i := range routes.Items {
+		route := &routes.Items[i]
+		if !r.isManaged(route) {
+			continue
+		}
+		for _, parentRef := range route.Spec.ParentRefs {
+			if isGatewayParentRef(parentRef) && string(parentRef.Name) == gateway.GetName() && parentNamespace(route, parentRef) == gateway.GetNamespace() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: route.Namespace, Name: route.Name}})
+				break
+			}
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *HTTPRouteReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return r.WatchDependencies(ctrl.NewControllerManagedBy(mgr).
+		For(&gatewayv1beta1.HTTPRoute{}, builder.WithPredicates(r.FilterEnabled())).
+		Watches(&source.Kind{Type: &gatewayv1beta1.Gateway{}}, handler.EnqueueRequestsFromMapFunc(r.findRoutesForGateway)), func() client.ObjectList { return &gatewayv1beta1.HTTPRouteList{} }).
+		Complete(r)
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+func newGateway(listeners ...gatewayv1beta1.Listener) *gatewayv1beta1.Gateway {
+	return &gatewayv1beta1.Gateway{
+		ObjectMeta: ctrl.ObjectMeta{Name: "gateway", Namespace: "infra"},
+		Spec:       gatewayv1beta1.GatewaySpec{GatewayClassName: "test", Listeners: listeners},
+	}
+}
+
+func newHTTPRoute(parentRefs ...gatewayv1beta1.ParentReference) *gatewayv1beta1.HTTPRoute {
+	return &gatewayv1beta1.HTTPRoute{
+		ObjectMeta: ctrl.ObjectMeta{Name: "route", Namespace: "default", Annotations: map[string]string{
+			monitorutil.GetUptimeRobotDomain():                                     "true",
+			monitorutil.GetUptimeRobotMonitorPrefix() + httputil.FriendlyNameField: "tester",
+		}},
+		Spec: gatewayv1beta1.HTTPRouteSpec{
+			CommonRouteSpec: gatewayv1beta1.CommonRouteSpec{ParentRefs: parentRefs},
+			Hostnames:       []gatewayv1beta1.Hostname{"a.example.com", "b.localhost", "*.example.com"},
+		},
+	}
+}
+
+func newGatewayScheme(t *testing.T) *runtime.Scheme {
+	s := runtime.NewScheme()
+	if err := gatewayv1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestHTTPRouteReconciler_buildRouteHostSchemeMap(t *testing.T) {
+	infra := gatewayv1beta1.Namespace("infra")
+	https := gatewayv1beta1.SectionName("https")
+	wildcard := gatewayv1beta1.Hostname("*.example.com")
+	gatewayRef := gatewayv1beta1.ParentReference{Name: "gateway", Namespace: &infra}
+	tests := []struct {
+		name    string
+		objects []client.Object
+		route   *gatewayv1beta1.HTTPRoute
+		want    map[string]string
+	}{
+		{name: "should use http without gateway", route: newHTTPRoute(gatewayRef), want: map[string]string{
+			"a.example.com": "http",
+			"b.localhost":   "http",
+		}},
+		{name: "should use https for hostnames of https listener", objects: []client.Object{newGateway(
+			gatewayv1beta1.Listener{Name: "http", Port: 80, Protocol: gatewayv1beta1.HTTPProtocolType},
+			gatewayv1beta1.Listener{Name: "https", Port: 443, Protocol: gatewayv1beta1.HTTPSProtocolType, Hostname: &wildcard},
+		)}, route: newHTTPRoute(gatewayRef), want: map[string]string{
+			"a.example.com": "https",
+			"b.localhost":   "http",
+		}},
+		{name: "should use https for all hostnames of https listener without hostname", objects: []client.Object{newGateway(
+			gatewayv1beta1.Listener{Name: "https", Port: 443, Protocol: gatewayv1beta1.HTTPSProtocolType},
+		)}, route: newHTTPRoute(gatewayRef), want: map[string]string{
+			"a.example.com": "https",
+			"b.localhost":   "https",
+		}},
+		{name: "should ignore https listener not selected by section name", objects: []client.Object{newGateway(
+			gatewayv1beta1.Listener{Name: "http", Port: 80, Protocol: gatewayv1beta1.HTTPProtocolType},
+			gatewayv1beta1.Listener{Name: "other", Port: 443, Protocol: gatewayv1beta1.HTTPSProtocolType},
+		)}, route: newHTTPRoute(gatewayv1beta1.ParentReference{Name: "gateway", Namespace: &infra, SectionName: &https}), want: map[string]string{
+			"a.example.com": "http",
+			"b.localhost":   "http",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &HTTPRouteReconciler{HostMonitorSyncer: HostMonitorSyncer{
+				Client: fake.NewClientBuilder().WithScheme(newGatewayScheme(t)).WithObjects(tt.objects...).Build(),
+			}}
+			if got := r.buildRouteHostSchemeMap(context.Background(), tt.route); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildRouteHostSchemeMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPRouteReconciler_Reconcile(t *testing.T) {
+	infra := gatewayv1beta1.Namespace("infra")
+	route := newHTTPRoute(gatewayv1beta1.ParentReference{Name: "gateway", Namespace: &infra})
+	gateway := newGateway(gatewayv1beta1.Listener{Name: "https", Port: 443, Protocol: gatewayv1beta1.HTTPSProtocolType})
+
+	testutilprovider := &testUtilProvider{}
+	testutilprovider.On("CreateMonitor", "", "https://a.example.com", matchHostAnnotations("tester-a.example.com", "")).Return("1", nil)
+	testutilprovider.On("CreateMonitor", "", "https://b.localhost", matchHostAnnotations("tester-b.localhost", "")).Return("2", nil)
+	defer testutilprovider.AssertExpectations(t)
+
+	r := &HTTPRouteReconciler{HostMonitorSyncer: HostMonitorSyncer{
+		Client:       fake.NewClientBuilder().WithScheme(newGatewayScheme(t)).WithObjects(route, gateway).Build(),
+		Recorder:     record.NewFakeRecorder(100),
+		UtilProvider: testutilprovider,
+	}}
+	tn := types.NamespacedName{Namespace: "default", Name: "route"}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: tn}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := &gatewayv1beta1.HTTPRoute{}
+	if err := r.Get(context.Background(), tn, got); err != nil {
+		t.Fatal(err)
+	}
+	if !controllerutil.ContainsFinalizer(got, monitorutil.GetUptimeRobotFinalizer()) {
+		t.Errorf("finalizer missing")
+	}
+	if got.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)] != "1,2" {
+		t.Errorf("monitor ids = %v, want %v", got.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)], "1,2")
+	}
+}
+
+// TestHTTPRouteReconciler_Reconcile_unreadableGateway makes sure that a gateway which can not be read is reported
+// rather than failing the sync, the hostnames it would accept are monitored with http.
+func TestHTTPRouteReconciler_Reconcile_unreadableGateway(t *testing.T) {
+	infra := gatewayv1beta1.Namespace("infra")
+	route := newHTTPRoute(gatewayv1beta1.ParentReference{Name: "gateway", Namespace: &infra})
+	// gateways are not registered so that reading them fails.
+	s := runtime.NewScheme()
+	s.AddKnownTypes(gatewayv1beta1.SchemeGroupVersion, &gatewayv1beta1.HTTPRoute{}, &gatewayv1beta1.HTTPRouteList{})
+	metav1.AddToGroupVersion(s, gatewayv1beta1.SchemeGroupVersion)
+
+	testutilprovider := &testUtilProvider{}
+	testutilprovider.On("CreateMonitor", "", "http://a.example.com", matchHostAnnotations("tester-a.example.com", "")).Return("1", nil)
+	testutilprovider.On("CreateMonitor", "", "http://b.localhost", matchHostAnnotations("tester-b.localhost", "")).Return("2", nil)
+	defer testutilprovider.AssertExpectations(t)
+
+	recorder := record.NewFakeRecorder(100)
+	r := &HTTPRouteReconciler{HostMonitorSyncer: HostMonitorSyncer{
+		Client:       fake.NewClientBuilder().WithScheme(s).WithObjects(route).Build(),
+		Recorder:     recorder,
+		UtilProvider: testutilprovider,
+	}}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "route"}}); err != nil {
+		t.Fatal(err)
+	}
+	if event := <-recorder.Events; !strings.HasPrefix(event, "Warning "+MonitorSyncFailedReason+" Gateway infra/gateway not successfully read") {
+		t.Errorf("event = %v, want a warning about the gateway", event)
+	}
+}
+
+func TestHTTPRouteReconciler_findRoutesForGateway(t *testing.T) {
+	infra := gatewayv1beta1.Namespace("infra")
+	attached := newHTTPRoute(gatewayv1beta1.ParentReference{Name: "gateway", Namespace: &infra})
+	detached := newHTTPRoute(gatewayv1beta1.ParentReference{Name: "gateway"})
+	detached.Name = "detached"
+	disabled := newHTTPRoute(gatewayv1beta1.ParentReference{Name: "gateway", Namespace: &infra})
+	disabled.Name = "disabled"
+	disabled.Annotations = nil
+
+	r := &HTTPRouteReconciler{HostMonitorSyncer: HostMonitorSyncer{
+		Client: fake.NewClientBuilder().WithScheme(newGatewayScheme(t)).WithObjects(attached, detached, disabled).Build(),
+	}}
+	got := r.findRoutesForGateway(newGateway())
+	want := []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "default", Name: "route"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findRoutesForGateway() = %v, want %v", got, want)
+	}
+}
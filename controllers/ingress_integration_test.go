@@ -0,0 +1,91 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	network "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Ingress monitors", func() {
+	const timeout = 10 * time.Second
+	const interval = 250 * time.Millisecond
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+
+	It("should create, update and delete the monitor of an ingress on UptimeRobot", func() {
+		ctx := context.Background()
+		ingress := &network.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Name: "tester", Namespace: "default", Annotations: map[string]string{
+				monitorutil.GetUptimeRobotDomain():  "true",
+				prefix + httputil.FriendlyNameField: "tester",
+				prefix + httputil.TypeField:         "HTTP",
+				prefix + monitorutil.Interval:       "300",
+			}},
+			Spec: network.IngressSpec{
+				Rules: []network.IngressRule{{Host: "test-domain.localhost"}},
+				TLS:   []network.IngressTLS{{Hosts: []string{"test-domain.localhost"}}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+
+		By("creating the monitor")
+		Eventually(func() string {
+			monitor, _ := fakeApi.FindMonitor("tester")
+			return monitor.Url()
+		}, timeout, interval).Should(Equal("https://test-domain.localhost"))
+		monitor, _ := fakeApi.FindMonitor("tester")
+		Expect(monitor.Fields[monitorutil.Interval]).To(Equal("300"))
+
+		key := types.NamespacedName{Namespace: "default", Name: "tester"}
+		Eventually(func() map[string]string {
+			_ = k8sClient.Get(ctx, key, ingress)
+			return ingress.Annotations
+		}, timeout, interval).Should(HaveKeyWithValue(monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation), monitorutil.StatusSynced))
+		Expect(ingress.Finalizers).To(ContainElement(monitorutil.GetUptimeRobotFinalizer()))
+
+		By("updating the monitor")
+		// the status annotations are patched concurrently by the controller.
+		Eventually(func() error {
+			if err := k8sClient.Get(ctx, key, ingress); err != nil {
+				return err
+			}
+			ingress.Annotations[prefix+monitorutil.Interval] = "60"
+			return k8sClient.Update(ctx, ingress)
+		}, timeout, interval).Should(Succeed())
+		Eventually(func() string {
+			monitor, _ := fakeApi.FindMonitor("tester")
+			return monitor.Fields[monitorutil.Interval]
+		}, timeout, interval).Should(Equal("60"))
+		Expect(fakeApi.Monitors()).To(HaveLen(1))
+
+		By("deleting the monitor")
+		Expect(k8sClient.Delete(ctx, ingress)).To(Succeed())
+		Eventually(func() bool {
+			return errors.IsNotFound(k8sClient.Get(ctx, key, ingress))
+		}, timeout, interval).Should(BeTrue())
+		Expect(fakeApi.Monitors()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,58 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	network "k8s.io/api/networking/v1"
+)
+
+// IngressClassAnnotation is the legacy annotation naming the class of ingresses without spec.ingressClassName.
+const IngressClassAnnotation = "kubernetes.io/ingress.class"
+
+// IngressClassFilter restricts the ingresses whose monitors are managed by their class, e.g. so that the hosts of an
+// internal ingress controller which can not be reached by UptimeRobot are not monitored.
+type IngressClassFilter struct {
+	// Allowed classes, the ingresses of every class or without class are admitted when empty.
+	Allowed []string
+	// Denied classes, they take precedence over Allowed.
+	Denied []string
+	// AutoEnabled classes whose admitted ingresses are monitored without the enable annotation, unless it is set.
+	AutoEnabled []string
+}
+
+// Admits reports whether the monitors of ingress may be managed according to its class.
+func (f *IngressClassFilter) Admits(ingress *network.Ingress) bool {
+	class := ingressClass(ingress)
+	if containsString(f.Denied, class) {
+		return false
+	}
+	return len(f.Allowed) == 0 || containsString(f.Allowed, class)
+}
+
+// AutoEnables reports whether ingress is monitored without the enable annotation because of its class.
+func (f *IngressClassFilter) AutoEnables(ingress *network.Ingress) bool {
+	class := ingressClass(ingress)
+	return len(class) > 0 && containsString(f.AutoEnabled, class)
+}
+
+// ingressClass returns the class of ingress, spec.ingressClassName takes precedence over the legacy annotation.
+func ingressClass(ingress *network.Ingress) string {
+	if ingress.Spec.IngressClassName != nil {
+		return *ingress.Spec.IngressClassName
+	}
+	return ingress.Annotations[IngressClassAnnotation]
+}
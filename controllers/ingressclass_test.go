@@ -0,0 +1,81 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	network "k8s.io/api/networking/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func newClassIngress(className string, annotatedClass string) *network.Ingress {
+	ingress := &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "ingress", Namespace: "default", Annotations: map[string]string{}}}
+	if len(className) > 0 {
+		ingress.Spec.IngressClassName = &className
+	}
+	if len(annotatedClass) > 0 {
+		ingress.Annotations[IngressClassAnnotation] = annotatedClass
+	}
+	return ingress
+}
+
+func TestIngressClassFilter_Admits(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  *IngressClassFilter
+		ingress *network.Ingress
+		want    bool
+	}{
+		{name: "should admit every class without allowed classes", filter: &IngressClassFilter{}, ingress: newClassIngress("internal", ""), want: true},
+		{name: "should admit allowed class", filter: &IngressClassFilter{Allowed: []string{"public"}}, ingress: newClassIngress("public", ""), want: true},
+		{name: "should not admit class not allowed", filter: &IngressClassFilter{Allowed: []string{"public"}}, ingress: newClassIngress("internal", ""), want: false},
+		{name: "should not admit ingress without class when classes are allowed", filter: &IngressClassFilter{Allowed: []string{"public"}}, ingress: newClassIngress("", ""), want: false},
+		{name: "should admit allowed class of the legacy annotation", filter: &IngressClassFilter{Allowed: []string{"public"}}, ingress: newClassIngress("", "public"), want: true},
+		{name: "should prefer ingressClassName over the legacy annotation", filter: &IngressClassFilter{Allowed: []string{"public"}}, ingress: newClassIngress("internal", "public"), want: false},
+		{name: "should not admit denied class", filter: &IngressClassFilter{Denied: []string{"internal"}}, ingress: newClassIngress("internal", ""), want: false},
+		{name: "should not admit denied class even if allowed", filter: &IngressClassFilter{Allowed: []string{"internal"}, Denied: []string{"internal"}}, ingress: newClassIngress("internal", ""), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Admits(tt.ingress); got != tt.want {
+				t.Errorf("Admits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngressClassFilter_AutoEnables(t *testing.T) {
+	filter := &IngressClassFilter{AutoEnabled: []string{"public"}}
+	tests := []struct {
+		name    string
+		ingress *network.Ingress
+		want    bool
+	}{
+		{name: "should auto enable class", ingress: newClassIngress("public", ""), want: true},
+		{name: "should auto enable class of the legacy annotation", ingress: newClassIngress("", "public"), want: true},
+		{name: "should not auto enable other class", ingress: newClassIngress("internal", ""), want: false},
+		{name: "should not auto enable ingress without class", ingress: newClassIngress("", ""), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filter.AutoEnables(tt.ingress); got != tt.want {
+				t.Errorf("AutoEnables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
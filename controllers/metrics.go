@@ -0,0 +1,132 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	monitoringv1alpha1 "github.com/bennsimon/uptimerobot-operator/api/v1alpha1"
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	network "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
+)
+
+var (
+	managedMonitorsDesc = prometheus.NewDesc("uptimerobot_operator_managed_monitors",
+		"Monitors managed by the operator by kind and namespace of their resource.", []string{"kind", "namespace"}, nil)
+	failedResourcesDesc = prometheus.NewDesc("uptimerobot_operator_failed_resources",
+		"Resources whose last sync of their monitors failed by kind and namespace.", []string{"kind", "namespace"}, nil)
+)
+
+func init() {
+	metrics.Registry.MustRegister(monitorutil.APICollectors()...)
+}
+
+// MonitorCollector reports the monitors managed by the operator and the resources whose last sync failed. They are
+// counted from the status of the resources on every scrape so that they also cover the resources synced before
+// the operator restarted.
+type MonitorCollector struct {
+	// Client should read from the manager's cache, the resources of every kind are listed on every scrape.
+	Client client.Reader
+	// Kinds of the resources owning monitors that are collected, e.g. without HTTPRouteKind when the Gateway API is
+	// not enabled.
+	Kinds []string
+}
+
+// ownerListKinds returns an empty list of each kind of resource that owns monitors.
+var ownerListKinds = map[string]func() client.ObjectList{
+	IngressKind:            func() client.ObjectList { return &network.IngressList{} },
+	UptimeRobotMonitorKind: func() client.ObjectList { return &monitoringv1alpha1.UptimeRobotMonitorList{} },
+	HTTPRouteKind:          func() client.ObjectList { return &gatewayv1beta1.HTTPRouteList{} },
+	ServiceKind:            func() client.ObjectList { return &corev1.ServiceList{} },
+}
+
+var _ prometheus.Collector = &MonitorCollector{}
+
+func (c *MonitorCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- managedMonitorsDesc
+	descs <- failedResourcesDesc
+}
+
+func (c *MonitorCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, kind := range c.Kinds {
+		newList, known := ownerListKinds[kind]
+		if !known {
+			continue
+		}
+		list := newList()
+		if err := c.Client.List(context.Background(), list); err != nil {
+			log.Log.Error(err, fmt.Sprintf("Resources of kind %s not successfully listed for the metrics", kind))
+			continue
+		}
+		objects, err := meta.ExtractList(list)
+		if err != nil {
+			log.Log.Error(err, fmt.Sprintf("Resources of kind %s not successfully listed for the metrics", kind))
+			continue
+		}
+		monitors := map[string]int{}
+		failed := map[string]int{}
+		for _, item := range objects {
+			object, ok := item.(client.Object)
+			if !ok {
+				continue
+			}
+			count, syncFailed := monitorState(object)
+			if count > 0 {
+				monitors[object.GetNamespace()] += count
+			}
+			if syncFailed {
+				failed[object.GetNamespace()]++
+			}
+		}
+		for namespace, count := range monitors {
+			ch <- prometheus.MustNewConstMetric(managedMonitorsDesc, prometheus.GaugeValue, float64(count), kind, namespace)
+		}
+		for namespace, count := range failed {
+			ch <- prometheus.MustNewConstMetric(failedResourcesDesc, prometheus.GaugeValue, float64(count), kind, namespace)
+		}
+	}
+}
+
+// monitorState returns the number of monitors of object as last synced and whether its last sync failed.
+func monitorState(object client.Object) (int, bool) {
+	if uptimeRobotMonitor, ok := object.(*monitoringv1alpha1.UptimeRobotMonitor); ok {
+		count := 0
+		if len(uptimeRobotMonitor.Status.MonitorID) > 0 {
+			count = 1
+		}
+		return count, meta.IsStatusConditionFalse(uptimeRobotMonitor.Status.Conditions, monitoringv1alpha1.ConditionReady)
+	}
+	annotations := object.GetAnnotations()
+	status, synced := annotations[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)]
+	if !synced {
+		return 0, false
+	}
+	count := 0
+	if ids := annotations[monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)]; len(ids) > 0 {
+		count = len(strings.Split(ids, ","))
+	}
+	return count, status == monitorutil.StatusFailed
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	monitoringv1alpha1 "github.com/bennsimon/uptimerobot-operator/api/v1alpha1"
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	network "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMonitorCollector_Collect(t *testing.T) {
+	statusKey := monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)
+	idKey := monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)
+	c := fake.NewClientBuilder().WithScheme(newTemplateScheme(t)).WithObjects(
+		&network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "synced", Namespace: "default", Annotations: map[string]string{statusKey: monitorutil.StatusSynced, idKey: "1,2"}}},
+		&network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "failed", Namespace: "default", Annotations: map[string]string{statusKey: monitorutil.StatusFailed, idKey: "3"}}},
+		&network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "failed", Namespace: "prod", Annotations: map[string]string{statusKey: monitorutil.StatusFailed}}},
+		&network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "unmanaged", Namespace: "prod"}},
+		&corev1.Service{ObjectMeta: ctrl.ObjectMeta{Name: "synced", Namespace: "prod", Annotations: map[string]string{statusKey: monitorutil.StatusSynced, idKey: "4"}}},
+		&monitoringv1alpha1.UptimeRobotMonitor{ObjectMeta: ctrl.ObjectMeta{Name: "synced", Namespace: "default"}, Status: monitoringv1alpha1.UptimeRobotMonitorStatus{
+			MonitorID:  "5",
+			Conditions: []metav1.Condition{{Type: monitoringv1alpha1.ConditionReady, Status: metav1.ConditionFalse}},
+		}},
+	).Build()
+
+	collector := &MonitorCollector{Client: c, Kinds: []string{IngressKind, ServiceKind, UptimeRobotMonitorKind}}
+	want := `
+# HELP uptimerobot_operator_failed_resources Resources whose last sync of their monitors failed by kind and namespace.
+# TYPE uptimerobot_operator_failed_resources gauge
+uptimerobot_operator_failed_resources{kind="Ingress",namespace="default"} 1
+uptimerobot_operator_failed_resources{kind="Ingress",namespace="prod"} 1
+uptimerobot_operator_failed_resources{kind="UptimeRobotMonitor",namespace="default"} 1
+# HELP uptimerobot_operator_managed_monitors Monitors managed by the operator by kind and namespace of their resource.
+# TYPE uptimerobot_operator_managed_monitors gauge
+uptimerobot_operator_managed_monitors{kind="Ingress",namespace="default"} 3
+uptimerobot_operator_managed_monitors{kind="Service",namespace="prod"} 1
+uptimerobot_operator_managed_monitors{kind="UptimeRobotMonitor",namespace="default"} 1
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// portMonitorType is the monitor type whose url is the bare address of the load balancer.
+const portMonitorType = "Port"
+
+// ServiceReconciler reconciles the monitors of Services of type LoadBalancer, a monitor is created for each
+// address of the load balancer.
+type ServiceReconciler struct {
+	HostMonitorSyncer
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;watch;list;update;patch
+
+func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	service := &corev1.Service{}
+	if err := r.Get(ctx, req.NamespacedName, service); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	hosts, err := buildServiceHostSchemeMap(service)
+	if err != nil {
+		log.Log.Error(err, fmt.Sprintf("Service %s/%s monitors not successfully built", service.Namespace, service.Name))
+		r.Recorder.Event(service, corev1.EventTypeWarning, MonitorSyncFailedReason, fmt.Sprintf("Service monitors not successfully built: %s", err))
+		return ctrl.Result{}, patchStatusAnnotations(ctx, r.Client, service, monitorutil.StatusFailed, nil, "")
+	}
+	// the service is reconciled again once the load balancer is provisioned. Services with recorded monitors are
+	// synced without hosts so that their monitors are released, e.g. once the load balancer lost its address.
+	if len(hosts) == 0 && service.DeletionTimestamp.IsZero() && len(recordedMonitors(service)) == 0 && !r.hasPendingDisable(service) {
+		log.Log.Info(fmt.Sprintf("Service %s/%s has no load balancer address yet", service.Namespace, service.Name))
+		return ctrl.Result{}, nil
+	}
+	return r.Sync(ctx, service, ServiceKind, hosts)
+}
+
+// buildServiceHostSchemeMap maps the addresses of the load balancer to the scheme of their monitor. Port monitors
+// watch the bare address with the port annotation, the other types watch the url of the address and the
+// annotated port, the first port of the service is used when it is not annotated.
+func buildServiceHostSchemeMap(service *corev1.Service) (map[string]string, error) {
+	hosts := map[string]string{}
+	if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return hosts, nil
+	}
+
+	monitorType := service.Annotations[monitorutil.GetUptimeRobotMonitorPrefix()+httputil.TypeField]
+	port, err := getServicePort(service)
+	if err != nil {
+		return nil, err
+	}
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		address := ingress.IP
+		if len(ingress.Hostname) > 0 {
+			address = ingress.Hostname
+		}
+		if len(address) == 0 {
+			continue
+		}
+		if strings.EqualFold(monitorType, portMonitorType) {
+			hosts[address] = ""
+		} else if strings.EqualFold(monitorType, "HTTPS") {
+			hosts[net.JoinHostPort(address, port)] = "https"
+		} else {
+			hosts[net.JoinHostPort(address, port)] = "http"
+		}
+	}
+	return hosts, nil
+}
+
+func getServicePort(service *corev1.Service) (string, error) {
+	if port, exists := service.Annotations[monitorutil.GetUptimeRobotMonitorPrefix()+httputil.PortField]; exists {
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return "", fmt.Errorf("invalid %s annotation %q", httputil.PortField, port)
+		}
+		return port, nil
+	}
+	if len(service.Spec.Ports) == 0 {
+		return "", fmt.Errorf("%s annotation is required by services without ports", httputil.PortField)
+	}
+	return strconv.Itoa(int(service.Spec.Ports[0].Port)), nil
+}
+
+// filterLoadBalancerChanges lets through the updates of the load balancer addresses of enabled services, they
+// are status changes dropped by FilterEnabled.
+func (r *ServiceReconciler) filterLoadBalancerChanges() predicate.Predicate {
+	return predicate.Funcs{CreateFunc: func(event.CreateEvent) bool {
+		return false
+	}, UpdateFunc: func(updateEvent event.UpdateEvent) bool {
+		oldService, ok := updateEvent.ObjectOld.(*corev1.Service)
+		if !ok {
+			return false
+		}
+		newService, ok := updateEvent.ObjectNew.(*corev1.Service)
+		if !ok {
+			return false
+		}
+		return r.isManaged(newService) &&
+			!reflect.DeepEqual(oldService.Status.LoadBalancer, newService.Status.LoadBalancer)
+	}, DeleteFunc: func(event.DeleteEvent) bool {
+		return false
+	}, GenericFunc: func(event.GenericEvent) bool {
+		return false
+	}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return r.WatchDependencies(ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Service{}, builder.WithPredicates(predicate.Or(r.FilterEnabled(), r.filterLoadBalancerChanges()))), func() client.ObjectList { return &corev1.ServiceList{} }).
+		Complete(r)
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func newLoadBalancerService(annotations map[string]string, ingress ...corev1.LoadBalancerIngress) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: ctrl.ObjectMeta{Name: "service", Namespace: "default", Annotations: annotations},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Name: "postgres", Port: 5432}},
+		},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: ingress}},
+	}
+}
+
+func Test_buildServiceHostSchemeMap(t *testing.T) {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	ip := corev1.LoadBalancerIngress{IP: "10.0.0.1"}
+	hostname := corev1.LoadBalancerIngress{Hostname: "lb.localhost"}
+	tests := []struct {
+		name    string
+		service *corev1.Service
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "should monitor bare addresses of port monitors", service: newLoadBalancerService(map[string]string{
+			prefix + httputil.TypeField: "Port",
+			prefix + httputil.PortField: "5432",
+		}, ip, hostname), want: map[string]string{"10.0.0.1": "", "lb.localhost": ""}},
+		{name: "should monitor url with annotated port", service: newLoadBalancerService(map[string]string{
+			prefix + httputil.TypeField: "HTTPS",
+			prefix + httputil.PortField: "8443",
+		}, hostname), want: map[string]string{"lb.localhost:8443": "https"}},
+		{name: "should default to the first port of the service", service: newLoadBalancerService(map[string]string{
+			prefix + httputil.TypeField: "HTTP",
+		}, ip), want: map[string]string{"10.0.0.1:5432": "http"}},
+		{name: "should return no hosts before the load balancer is provisioned", service: newLoadBalancerService(map[string]string{
+			prefix + httputil.TypeField: "Port",
+		}), want: map[string]string{}},
+		{name: "should return error on invalid port", service: newLoadBalancerService(map[string]string{
+			prefix + httputil.PortField: "http",
+		}, ip), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildServiceHostSchemeMap(tt.service)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildServiceHostSchemeMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildServiceHostSchemeMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildServiceHostSchemeMap_ClusterIP(t *testing.T) {
+	service := newLoadBalancerService(nil, corev1.LoadBalancerIngress{IP: "10.0.0.1"})
+	service.Spec.Type = corev1.ServiceTypeClusterIP
+	got, err := buildServiceHostSchemeMap(service)
+	if err != nil || len(got) > 0 {
+		t.Errorf("buildServiceHostSchemeMap() = %v, %v, want no hosts", got, err)
+	}
+}
+
+func TestServiceReconciler_Reconcile(t *testing.T) {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	service := newLoadBalancerService(map[string]string{
+		monitorutil.GetUptimeRobotDomain():  "true",
+		prefix + httputil.FriendlyNameField: "tester",
+		prefix + httputil.TypeField:         "Port",
+		prefix + httputil.PortField:         "5432",
+	}, corev1.LoadBalancerIngress{IP: "10.0.0.1"})
+
+	testutilprovider := &testUtilProvider{}
+	testutilprovider.On("CreateMonitor", "", "10.0.0.1", matchHostAnnotations("tester", "")).Return("1", nil)
+	defer testutilprovider.AssertExpectations(t)
+
+	r := &ServiceReconciler{HostMonitorSyncer: HostMonitorSyncer{
+		Client:       fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(service).Build(),
+		Recorder:     record.NewFakeRecorder(100),
+		UtilProvider: testutilprovider,
+	}}
+	tn := types.NamespacedName{Namespace: "default", Name: "service"}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: tn}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := &corev1.Service{}
+	if err := r.Get(context.Background(), tn, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)] != "1" {
+		t.Errorf("monitor ids = %v, want %v", got.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)], "1")
+	}
+}
+
+// TestServiceReconciler_Reconcile_lostAddress makes sure that the monitors of a load balancer that lost its address are
+// released rather than left behind.
+func TestServiceReconciler_Reconcile_lostAddress(t *testing.T) {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	service := newLoadBalancerService(map[string]string{
+		monitorutil.GetUptimeRobotDomain():  "true",
+		prefix + httputil.FriendlyNameField: "tester",
+		prefix + httputil.TypeField:         "Port",
+		prefix + httputil.PortField:         "5432",
+	}, corev1.LoadBalancerIngress{IP: "10.0.0.1"})
+
+	testutilprovider := &testUtilProvider{}
+	testutilprovider.On("CreateMonitor", "", "10.0.0.1", matchHostAnnotations("tester", "")).Return("1", nil)
+	testutilprovider.On("DeleteMonitorById", "", "1").Return(nil)
+	defer testutilprovider.AssertExpectations(t)
+
+	r := &ServiceReconciler{HostMonitorSyncer: HostMonitorSyncer{
+		Client:       fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(service).Build(),
+		Recorder:     record.NewFakeRecorder(100),
+		UtilProvider: testutilprovider,
+	}}
+	tn := types.NamespacedName{Namespace: "default", Name: "service"}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: tn}); err != nil {
+		t.Fatal(err)
+	}
+	got := &corev1.Service{}
+	if err := r.Get(context.Background(), tn, got); err != nil {
+		t.Fatal(err)
+	}
+	got.Status.LoadBalancer.Ingress = nil
+	if err := r.Update(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: tn}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Get(context.Background(), tn, got); err != nil {
+		t.Fatal(err)
+	}
+	if monitors := recordedMonitors(got); len(monitors) > 0 {
+		t.Errorf("recorded monitors = %v, want none", monitors)
+	}
+}
+
+func TestServiceReconciler_filterLoadBalancerChanges(t *testing.T) {
+	enabled := map[string]string{monitorutil.GetUptimeRobotDomain(): "true"}
+	pending := newLoadBalancerService(enabled)
+	provisioned := newLoadBalancerService(enabled, corev1.LoadBalancerIngress{IP: "10.0.0.1"})
+	disabled := newLoadBalancerService(nil, corev1.LoadBalancerIngress{IP: "10.0.0.1"})
+	tests := []struct {
+		name      string
+		oldObject *corev1.Service
+		newObject *corev1.Service
+		want      bool
+	}{
+		{name: "should pass load balancer changes", oldObject: pending, newObject: provisioned, want: true},
+		{name: "should drop unchanged load balancer", oldObject: provisioned, newObject: provisioned.DeepCopy(), want: false},
+		{name: "should drop disabled services", oldObject: newLoadBalancerService(nil), newObject: disabled, want: false},
+	}
+	r := &ServiceReconciler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.filterLoadBalancerChanges().Update(event.UpdateEvent{ObjectOld: tt.oldObject, ObjectNew: tt.newObject}); got != tt.want {
+				t.Errorf("filterLoadBalancerChanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
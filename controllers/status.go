@@ -0,0 +1,173 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Reasons of the events recorded on resources whose monitors are managed by the operator.
+const (
+	MonitorSyncedReason        = "MonitorSynced"
+	MonitorSyncFailedReason    = "MonitorSyncFailed"
+	MonitorDeletedReason       = "MonitorDeleted"
+	MonitorDeleteFailedReason  = "MonitorDeleteFailed"
+	MonitorDriftedReason       = "MonitorDrifted"
+	MonitorPausedReason        = "MonitorPaused"
+	MonitorDisableFailedReason = "MonitorDisableFailed"
+	// MonitorAdoptedReason reports what adopting an existing monitor changed, MonitorAdoptionPreviewReason what it
+	// would change.
+	MonitorAdoptedReason         = "MonitorAdopted"
+	MonitorAdoptionPreviewReason = "MonitorAdoptionPreview"
+)
+
+// managedMonitor is a monitor created for a host of a resource, recorded by the managed-monitors annotation.
+type managedMonitor struct {
+	Id string `json:"id"`
+	// Paused is set once the host was removed from the resource and its monitor paused rather than deleted.
+	Paused bool `json:"paused,omitempty"`
+}
+
+// patchStatusAnnotations records the outcome of a sync on the object. The monitors, last synced time and applied
+// hash are only replaced on success so that they keep pointing at the last known state on failure. monitors maps
+// the url of each monitor to its id, the ids of the monitors which are not paused are recorded by the id annotation.
+func patchStatusAnnotations(ctx context.Context, c client.Client, object client.Object, status string, monitors map[string]managedMonitor, appliedHash string) error {
+	patch := client.MergeFrom(object.DeepCopyObject().(client.Object))
+	annotations := object.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)] = status
+	if status == monitorutil.StatusSynced {
+		monitorIds := make([]string, 0, len(monitors))
+		urls := make([]string, 0, len(monitors))
+		for url := range monitors {
+			urls = append(urls, url)
+		}
+		sort.Strings(urls)
+		for _, url := range urls {
+			if !monitors[url].Paused {
+				monitorIds = append(monitorIds, monitors[url].Id)
+			}
+		}
+		managedMonitors, err := json.Marshal(monitors)
+		if err != nil {
+			return err
+		}
+		annotations[monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)] = strings.Join(monitorIds, ",")
+		annotations[monitorutil.GetStatusAnnotationKey(monitorutil.ManagedMonitorsAnnotation)] = string(managedMonitors)
+		annotations[monitorutil.GetStatusAnnotationKey(monitorutil.LastSyncedAnnotation)] = time.Now().UTC().Format(time.RFC3339)
+		annotations[monitorutil.GetStatusAnnotationKey(monitorutil.LastAppliedAnnotation)] = appliedHash
+	}
+	object.SetAnnotations(annotations)
+	return c.Patch(ctx, object, patch)
+}
+
+// recordedMonitors returns the monitors of object recorded by its last successful sync by their url. Resources last
+// synced before the managed-monitors annotation only recorded the ids of their monitors, they are keyed by id.
+func recordedMonitors(object client.Object) map[string]managedMonitor {
+	annotations := object.GetAnnotations()
+	monitors := map[string]managedMonitor{}
+	if value, exists := annotations[monitorutil.GetStatusAnnotationKey(monitorutil.ManagedMonitorsAnnotation)]; exists {
+		err := json.Unmarshal([]byte(value), &monitors)
+		if err == nil {
+			return monitors
+		}
+		log.Log.Error(err, fmt.Sprintf("Managed monitors of %s/%s not successfully parsed", object.GetNamespace(), object.GetName()))
+		monitors = map[string]managedMonitor{}
+	}
+	if ids := annotations[monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)]; len(ids) > 0 {
+		for _, id := range strings.Split(ids, ",") {
+			monitors[id] = managedMonitor{Id: id}
+		}
+	}
+	return monitors
+}
+
+// isApplied reports whether the monitors described by the annotations and hosts were last applied successfully,
+// in which case they only need to be applied again if they drifted on UptimeRobot.
+func isApplied(annotations map[string]string, hosts map[string]string, labels map[string]string) bool {
+	return annotations[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)] == monitorutil.StatusSynced &&
+		annotations[monitorutil.GetStatusAnnotationKey(monitorutil.LastAppliedAnnotation)] == desiredStateHash(annotations, hosts, labels)
+}
+
+// desiredStateHash hashes the monitor annotations and hosts, the status annotations are left out. The labels are
+// only hashed when templated annotation values may reference them, so that the hash of other resources is stable.
+func desiredStateHash(annotations map[string]string, hosts map[string]string, labels map[string]string) string {
+	hash := sha256.New()
+	monitorAnnotations := withoutStatusAnnotations(annotations)
+	for _, key := range sortedKeys(monitorAnnotations) {
+		if strings.HasPrefix(key, monitorutil.GetUptimeRobotDomain()) {
+			fmt.Fprintf(hash, "%s=%s\n", key, monitorAnnotations[key])
+		}
+	}
+	for _, host := range sortedKeys(hosts) {
+		fmt.Fprintf(hash, "%s://%s\n", hosts[host], host)
+	}
+	if monitorutil.HasTemplatedValues(monitorAnnotations) {
+		for _, key := range sortedKeys(labels) {
+			fmt.Fprintf(hash, "label %s=%s\n", key, labels[key])
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// retryableSyncError returns err when the failed sync may succeed on retry so that the resource is requeued with
+// the controller's exponential backoff. Permanent errors are only reported, retrying them would hot-loop until
+// the resource is fixed, which triggers a new reconcile anyway.
+func retryableSyncError(err error) error {
+	if monitorutil.IsTransientError(err) {
+		return err
+	}
+	return nil
+}
+
+// hasOnlyStatusChanges reports whether the objects differ by nothing but the status annotations written
+// by the operator, such updates must not trigger another reconcile.
+func hasOnlyStatusChanges(oldObject client.Object, newObject client.Object) bool {
+	if oldObject == nil || newObject == nil {
+		return false
+	}
+	if oldObject.GetGeneration() != newObject.GetGeneration() ||
+		!oldObject.GetDeletionTimestamp().Equal(newObject.GetDeletionTimestamp()) ||
+		!reflect.DeepEqual(oldObject.GetLabels(), newObject.GetLabels()) {
+		return false
+	}
+	return reflect.DeepEqual(withoutStatusAnnotations(oldObject.GetAnnotations()), withoutStatusAnnotations(newObject.GetAnnotations()))
+}
+
+func withoutStatusAnnotations(annotations map[string]string) map[string]string {
+	filtered := map[string]string{}
+	for key, value := range annotations {
+		if !monitorutil.IsStatusAnnotation(key) {
+			filtered[key] = value
+		}
+	}
+	return filtered
+}
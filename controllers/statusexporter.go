@@ -0,0 +1,172 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/prometheus/client_golang/prometheus"
+	network "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// the monitors of an ingress are told apart by their id, its hosts may have a monitor for each of their paths.
+var (
+	monitorUpDesc = prometheus.NewDesc("uptimerobot_monitor_up",
+		"Whether the monitor is up on UptimeRobot, paused monitors and monitors not checked yet are not up.",
+		[]string{"namespace", "ingress", "host", "monitor_id"}, nil)
+	monitorResponseTimeDesc = prometheus.NewDesc("uptimerobot_monitor_response_time_ms",
+		"Average response time of the monitor on UptimeRobot in milliseconds.",
+		[]string{"namespace", "ingress", "host", "monitor_id"}, nil)
+	monitorUptimeRatioDesc = prometheus.NewDesc("uptimerobot_monitor_uptime_ratio",
+		"All time uptime ratio of the monitor on UptimeRobot, between 0 and 1.",
+		[]string{"namespace", "ingress", "host", "monitor_id"}, nil)
+)
+
+// MonitorStatusExporter periodically fetches the live status of the monitors of the managed ingresses from
+// UptimeRobot and exposes it as metrics. The monitors of an account are fetched in batches, and an account whose
+// requests fail, e.g. because of its rate limit, keeps the statuses of the previous poll.
+type MonitorStatusExporter struct {
+	// Client should read from the manager's cache, the ingresses are listed on every poll.
+	client.Client
+	Interval       time.Duration
+	APIKeyResolver *APIKeyResolver
+	UtilProvider
+
+	mu sync.Mutex
+	// statuses of the last poll by monitor id.
+	statuses map[string]exportedStatus
+}
+
+// exportedStatus is the live status of a monitor with the ingress it belongs to.
+type exportedStatus struct {
+	monitorutil.MonitorStatus
+	Namespace string
+	Ingress   string
+}
+
+var _ manager.Runnable = &MonitorStatusExporter{}
+var _ manager.LeaderElectionRunnable = &MonitorStatusExporter{}
+var _ prometheus.Collector = &MonitorStatusExporter{}
+
+func (e *MonitorStatusExporter) Start(ctx context.Context) error {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := e.poll(ctx); err != nil {
+			log.Log.Error(err, "Monitor statuses not successfully exported")
+		}
+	}, e.Interval)
+	return nil
+}
+
+// NeedLeaderElection makes sure that only one replica polls UptimeRobot, the others export no statuses.
+func (e *MonitorStatusExporter) NeedLeaderElection() bool {
+	return true
+}
+
+func (e *MonitorStatusExporter) Describe(descs chan<- *prometheus.Desc) {
+	descs <- monitorUpDesc
+	descs <- monitorResponseTimeDesc
+	descs <- monitorUptimeRatioDesc
+}
+
+func (e *MonitorStatusExporter) Collect(ch chan<- prometheus.Metric) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, status := range e.statuses {
+		host := monitorHost(status.Url)
+		up := 0.0
+		if status.Status == monitorutil.MonitorUp {
+			up = 1
+		}
+		ch <- prometheus.MustNewConstMetric(monitorUpDesc, prometheus.GaugeValue, up, status.Namespace, status.Ingress, host, status.Id)
+		ch <- prometheus.MustNewConstMetric(monitorResponseTimeDesc, prometheus.GaugeValue, status.ResponseTime, status.Namespace, status.Ingress, host, status.Id)
+		ch <- prometheus.MustNewConstMetric(monitorUptimeRatioDesc, prometheus.GaugeValue, status.UptimeRatio/100, status.Namespace, status.Ingress, host, status.Id)
+	}
+}
+
+// poll fetches the statuses of the monitors of the synced ingresses, grouped by UptimeRobot account.
+func (e *MonitorStatusExporter) poll(ctx context.Context) error {
+	ingresses := &network.IngressList{}
+	if err := e.List(ctx, ingresses); err != nil {
+		return err
+	}
+	idsByApiKey := map[string][]string{}
+	owners := map[string]*network.Ingress{}
+	for i := range ingresses.Items {
+		ingress := &ingresses.Items[i]
+		ids := ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)]
+		if len(ids) == 0 {
+			continue
+		}
+		apiKey, err := e.APIKeyResolver.Resolve(ctx, ingress)
+		if err != nil {
+			log.Log.Error(err, fmt.Sprintf("Api key of Ingress %s/%s not successfully resolved", ingress.Namespace, ingress.Name))
+			continue
+		}
+		for _, id := range strings.Split(ids, ",") {
+			if len(id) == 0 {
+				continue
+			}
+			idsByApiKey[apiKey] = append(idsByApiKey[apiKey], id)
+			owners[id] = ingress
+		}
+	}
+
+	e.mu.Lock()
+	previous := e.statuses
+	e.mu.Unlock()
+	statuses := map[string]exportedStatus{}
+	for apiKey, ids := range idsByApiKey {
+		monitorStatuses, err := e.UtilProvider.GetMonitorStatuses(ctx, apiKey, ids)
+		if err != nil {
+			log.Log.Error(err, fmt.Sprintf("Statuses of %d monitors not successfully fetched, the previous statuses are kept", len(ids)))
+			for _, id := range ids {
+				if status, found := previous[id]; found {
+					statuses[id] = status
+				}
+			}
+			continue
+		}
+		for _, status := range monitorStatuses {
+			if owner, found := owners[status.Id]; found {
+				statuses[status.Id] = exportedStatus{MonitorStatus: status, Namespace: owner.Namespace, Ingress: owner.Name}
+			}
+		}
+	}
+
+	e.mu.Lock()
+	e.statuses = statuses
+	e.mu.Unlock()
+	return nil
+}
+
+// monitorHost returns the host of the url of a monitor, monitors of a bare address e.g. Port monitors have no scheme.
+func monitorHost(monitorUrl string) string {
+	if _, afterScheme, found := strings.Cut(monitorUrl, "://"); found {
+		monitorUrl = afterScheme
+	}
+	host, _ := monitorutil.SplitTarget(monitorUrl)
+	return host
+}
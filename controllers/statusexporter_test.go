@@ -0,0 +1,117 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	network "k8s.io/api/networking/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMonitorStatusExporter_poll(t *testing.T) {
+	idKey := monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)
+	c := fake.NewClientBuilder().WithObjects(
+		&network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{idKey: "1,2"}}},
+		&network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "unsynced", Namespace: "default"}},
+	).Build()
+
+	testutilprovider := &testUtilProvider{}
+	testutilprovider.On("GetMonitorStatuses", "", []string{"1", "2"}).Return([]monitorutil.MonitorStatus{
+		{Id: "1", Url: "https://a.local", Status: monitorutil.MonitorUp, ResponseTime: 120, UptimeRatio: 99.5},
+		{Id: "2", Url: "https://b.local/healthz", Status: monitorutil.MonitorDown, ResponseTime: 0, UptimeRatio: 50},
+	}, nil).Once()
+	testutilprovider.On("GetMonitorStatuses", "", []string{"1", "2"}).Return(nil, errors.New("rate limited")).Once()
+	defer testutilprovider.AssertExpectations(t)
+
+	e := &MonitorStatusExporter{Client: c, UtilProvider: testutilprovider}
+	want := `
+# HELP uptimerobot_monitor_up Whether the monitor is up on UptimeRobot, paused monitors and monitors not checked yet are not up.
+# TYPE uptimerobot_monitor_up gauge
+uptimerobot_monitor_up{host="a.local",ingress="web",monitor_id="1",namespace="default"} 1
+uptimerobot_monitor_up{host="b.local",ingress="web",monitor_id="2",namespace="default"} 0
+# HELP uptimerobot_monitor_response_time_ms Average response time of the monitor on UptimeRobot in milliseconds.
+# TYPE uptimerobot_monitor_response_time_ms gauge
+uptimerobot_monitor_response_time_ms{host="a.local",ingress="web",monitor_id="1",namespace="default"} 120
+uptimerobot_monitor_response_time_ms{host="b.local",ingress="web",monitor_id="2",namespace="default"} 0
+# HELP uptimerobot_monitor_uptime_ratio All time uptime ratio of the monitor on UptimeRobot, between 0 and 1.
+# TYPE uptimerobot_monitor_uptime_ratio gauge
+uptimerobot_monitor_uptime_ratio{host="a.local",ingress="web",monitor_id="1",namespace="default"} 0.995
+uptimerobot_monitor_uptime_ratio{host="b.local",ingress="web",monitor_id="2",namespace="default"} 0.5
+`
+	for _, poll := range []string{"first poll", "poll keeping the previous statuses on error"} {
+		if err := e.poll(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := testutil.CollectAndCompare(e, strings.NewReader(want)); err != nil {
+			t.Errorf("%s: %s", poll, err)
+		}
+	}
+}
+
+func TestMonitorStatusExporter_poll_pathsOfHost(t *testing.T) {
+	idKey := monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)
+	c := fake.NewClientBuilder().WithObjects(
+		&network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{idKey: "1,2"}}},
+	).Build()
+
+	testutilprovider := &testUtilProvider{}
+	testutilprovider.On("GetMonitorStatuses", "", []string{"1", "2"}).Return([]monitorutil.MonitorStatus{
+		{Id: "1", Url: "https://a.local/api", Status: monitorutil.MonitorUp},
+		{Id: "2", Url: "https://a.local/healthz", Status: monitorutil.MonitorDown},
+	}, nil)
+	defer testutilprovider.AssertExpectations(t)
+
+	e := &MonitorStatusExporter{Client: c, UtilProvider: testutilprovider}
+	if err := e.poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := `
+# HELP uptimerobot_monitor_up Whether the monitor is up on UptimeRobot, paused monitors and monitors not checked yet are not up.
+# TYPE uptimerobot_monitor_up gauge
+uptimerobot_monitor_up{host="a.local",ingress="web",monitor_id="1",namespace="default"} 1
+uptimerobot_monitor_up{host="a.local",ingress="web",monitor_id="2",namespace="default"} 0
+`
+	if err := testutil.CollectAndCompare(e, strings.NewReader(want), "uptimerobot_monitor_up"); err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_monitorHost(t *testing.T) {
+	tests := []struct {
+		name       string
+		monitorUrl string
+		want       string
+	}{
+		{name: "should return host of url", monitorUrl: "https://a.local", want: "a.local"},
+		{name: "should return host of url with path", monitorUrl: "http://a.local/healthz", want: "a.local"},
+		{name: "should return bare address", monitorUrl: "10.0.0.1", want: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := monitorHost(tt.monitorUrl); got != tt.want {
+				t.Errorf("monitorHost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -17,14 +17,20 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
+	"os"
 	"path/filepath"
 	"testing"
 
+	monitoringv1alpha1 "github.com/bennsimon/uptimerobot-operator/api/v1alpha1"
+	"github.com/bennsimon/uptimerobot-operator/util/fakeuptimerobot"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -39,6 +45,10 @@ var cfg *rest.Config
 var k8sClient client.Client
 var testEnv *envtest.Environment
 
+// fakeApi is the UptimeRobot account the monitors of the suite are created on.
+var fakeApi *fakeuptimerobot.Server
+var cancel context.CancelFunc
+
 func TestAPIs(t *testing.T) {
 	RegisterFailHandler(Fail)
 
@@ -48,6 +58,15 @@ func TestAPIs(t *testing.T) {
 var _ = BeforeSuite(func() {
 	logf.SetLogger(zap.New(zap.WriteTo(GinkgoWriter), zap.UseDevMode(true)))
 
+	// the suite needs the control plane binaries, e.g. installed by make test. It is only skipped locally, CI
+	// installs them so that the suite does not pass by being skipped.
+	if len(os.Getenv("KUBEBUILDER_ASSETS")) == 0 {
+		if len(os.Getenv("CI")) > 0 {
+			Fail("KUBEBUILDER_ASSETS is not set")
+		}
+		Skip("KUBEBUILDER_ASSETS is not set")
+	}
+
 	By("bootstrapping test environment")
 	testEnv = &envtest.Environment{
 		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
@@ -60,16 +79,54 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 	Expect(cfg).NotTo(BeNil())
 
+	err = monitoringv1alpha1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	//+kubebuilder:scaffold:scheme
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
 
+	By("starting the fake UptimeRobot api")
+	fakeApi = fakeuptimerobot.NewServer("test-api-key")
+	Expect(os.Setenv(httputil.UptimeRobotApiKeyEnv, fakeApi.APIKey)).To(Succeed())
+	Expect(os.Setenv(httputil.UptimeRobotApiUrlEnv, fakeApi.BaseURL())).To(Succeed())
+
+	By("starting the controllers")
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{Scheme: scheme.Scheme, MetricsBindAddress: "0"})
+	Expect(err).NotTo(HaveOccurred())
+	err = (&UptimerobotReconciler{
+		HostMonitorSyncer: HostMonitorSyncer{
+			Client:       mgr.GetClient(),
+			Recorder:     mgr.GetEventRecorderFor("uptimerobot-operator"),
+			UtilProvider: &MonitorUtilProvider{},
+		},
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	var ctx context.Context
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		defer GinkgoRecover()
+		Expect(mgr.Start(ctx)).To(Succeed())
+	}()
 })
 
 var _ = AfterSuite(func() {
+	if testEnv == nil {
+		return
+	}
 	By("tearing down the test environment")
+	if cancel != nil {
+		cancel()
+	}
+	if fakeApi != nil {
+		fakeApi.Close()
+		Expect(os.Unsetenv(httputil.UptimeRobotApiKeyEnv)).To(Succeed())
+		Expect(os.Unsetenv(httputil.UptimeRobotApiUrlEnv)).To(Succeed())
+	}
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
 })
@@ -0,0 +1,188 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	monitoringv1alpha1 "github.com/bennsimon/uptimerobot-operator/api/v1alpha1"
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TemplateResolver resolves the default monitor parameters of a resource from the MonitorTemplates and
+// ClusterMonitorTemplates it names through the template annotation or whose selectors match it.
+type TemplateResolver struct {
+	// Client reads the templates and namespaces, it should read from the manager's cache.
+	Client client.Reader
+}
+
+// +kubebuilder:rbac:groups=monitoring.bennsimon.github.io,resources=monitortemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=monitoring.bennsimon.github.io,resources=clustermonitortemplates,verbs=get;list;watch
+
+// Resolve returns the default monitor parameters of object, they are merged from the lowest to the highest
+// precedence out of:
+//  1. the ClusterMonitorTemplates matching object,
+//  2. the MonitorTemplates of the namespace of object matching it,
+//  3. the template named by the template annotation, a MonitorTemplate of the namespace of object or else a
+//     ClusterMonitorTemplate.
+//
+// Templates of the same precedence are merged in name order. The annotations of object take precedence over
+// all of them, see monitorutil.WithTemplateParameters.
+func (r *TemplateResolver) Resolve(ctx context.Context, object client.Object) (map[string]string, error) {
+	if r == nil {
+		return nil, nil
+	}
+	parameters := map[string]string{}
+	merge := func(spec monitoringv1alpha1.MonitorTemplateSpec) {
+		for key, value := range spec.Parameters {
+			parameters[key] = value
+		}
+	}
+
+	clusterTemplates := &monitoringv1alpha1.ClusterMonitorTemplateList{}
+	if err := r.Client.List(ctx, clusterTemplates); err != nil {
+		return nil, err
+	}
+	var namespaceLabels map[string]string
+	for i := range clusterTemplates.Items {
+		template := &clusterTemplates.Items[i]
+		if template.Spec.NamespaceSelector != nil && namespaceLabels == nil {
+			namespace := &corev1.Namespace{}
+			if err := r.Client.Get(ctx, types.NamespacedName{Name: object.GetNamespace()}, namespace); err != nil {
+				return nil, err
+			}
+			namespaceLabels = namespace.Labels
+			if namespaceLabels == nil {
+				namespaceLabels = map[string]string{}
+			}
+		}
+		if matchesClusterTemplate(template, object, namespaceLabels) {
+			merge(template.Spec.MonitorTemplateSpec)
+		}
+	}
+
+	templates := &monitoringv1alpha1.MonitorTemplateList{}
+	if err := r.Client.List(ctx, templates, client.InNamespace(object.GetNamespace())); err != nil {
+		return nil, err
+	}
+	for i := range templates.Items {
+		if matchesSelector(templates.Items[i].Spec.Selector, object.GetLabels()) {
+			merge(templates.Items[i].Spec)
+		}
+	}
+
+	name, named := object.GetAnnotations()[monitorutil.GetUptimeRobotMonitorPrefix()+monitorutil.TemplateAnnotation]
+	if !named {
+		return parameters, nil
+	}
+	template := &monitoringv1alpha1.MonitorTemplate{}
+	err := r.Client.Get(ctx, types.NamespacedName{Namespace: object.GetNamespace(), Name: name}, template)
+	if err == nil {
+		merge(template.Spec)
+		return parameters, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	clusterTemplate := &monitoringv1alpha1.ClusterMonitorTemplate{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, clusterTemplate); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("monitor template %s not found in namespace %s nor cluster wide", name, object.GetNamespace())
+		}
+		return nil, err
+	}
+	merge(clusterTemplate.Spec.MonitorTemplateSpec)
+	return parameters, nil
+}
+
+// uses reports whether template may supply parameters to object, namespace selectors are assumed to match.
+func (r *TemplateResolver) uses(object client.Object, template client.Object) bool {
+	if name, named := object.GetAnnotations()[monitorutil.GetUptimeRobotMonitorPrefix()+monitorutil.TemplateAnnotation]; named && name == template.GetName() &&
+		(len(template.GetNamespace()) == 0 || template.GetNamespace() == object.GetNamespace()) {
+		return true
+	}
+	switch template := template.(type) {
+	case *monitoringv1alpha1.MonitorTemplate:
+		return template.Namespace == object.GetNamespace() && matchesSelector(template.Spec.Selector, object.GetLabels())
+	case *monitoringv1alpha1.ClusterMonitorTemplate:
+		if template.Spec.Selector != nil {
+			return matchesSelector(template.Spec.Selector, object.GetLabels())
+		}
+		return template.Spec.NamespaceSelector != nil
+	}
+	return false
+}
+
+// matchesClusterTemplate reports whether the selectors of template match object, templates without selector
+// only apply to the resources naming them.
+func matchesClusterTemplate(template *monitoringv1alpha1.ClusterMonitorTemplate, object client.Object, namespaceLabels map[string]string) bool {
+	if template.Spec.Selector == nil && template.Spec.NamespaceSelector == nil {
+		return false
+	}
+	return (template.Spec.Selector == nil || matchesSelector(template.Spec.Selector, object.GetLabels())) &&
+		(template.Spec.NamespaceSelector == nil || matchesSelector(template.Spec.NamespaceSelector, namespaceLabels))
+}
+
+// matchesSelector reports whether the labels match selector, a nil or invalid selector matches nothing.
+func matchesSelector(selector *metav1.LabelSelector, objectLabels map[string]string) bool {
+	if selector == nil {
+		return false
+	}
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		log.Log.Error(err, "Monitor template selector not successfully parsed")
+		return false
+	}
+	return labelSelector.Matches(labels.Set(objectLabels))
+}
+
+// EnqueueTemplateUsers returns the handler enqueuing the enabled resources using a template when it changes, so
+// that its parameters are applied without waiting for the resync. newList creates an empty list of the resources.
+func (r *HostMonitorSyncer) EnqueueTemplateUsers(newList func() client.ObjectList) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(template client.Object) []reconcile.Request {
+		list := newList()
+		if err := r.List(context.Background(), list, client.InNamespace(template.GetNamespace())); err != nil {
+			log.Log.Error(err, fmt.Sprintf("Users of monitor template %s not successfully listed", template.GetName()))
+			return nil
+		}
+		objects, err := meta.ExtractList(list)
+		if err != nil {
+			log.Log.Error(err, fmt.Sprintf("Users of monitor template %s not successfully listed", template.GetName()))
+			return nil
+		}
+		requests := make([]reconcile.Request, 0)
+		for _, item := range objects {
+			object, ok := item.(client.Object)
+			if !ok || !r.isManaged(object) || !r.TemplateResolver.uses(object, template) {
+				continue
+			}
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: object.GetNamespace(), Name: object.GetName()}})
+		}
+		return requests
+	})
+}
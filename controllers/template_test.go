@@ -0,0 +1,172 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	monitoringv1alpha1 "github.com/bennsimon/uptimerobot-operator/api/v1alpha1"
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	network "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTemplateScheme(t *testing.T) *runtime.Scheme {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := monitoringv1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newTemplateObjects() []client.Object {
+	return []client.Object{
+		&corev1.Namespace{ObjectMeta: ctrl.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: ctrl.ObjectMeta{Name: "prod", Labels: map[string]string{"environment": "production"}}},
+		&monitoringv1alpha1.ClusterMonitorTemplate{ObjectMeta: ctrl.ObjectMeta{Name: "production"}, Spec: monitoringv1alpha1.ClusterMonitorTemplateSpec{
+			MonitorTemplateSpec: monitoringv1alpha1.MonitorTemplateSpec{Parameters: map[string]string{monitorutil.Interval: "60", httputil.AlertContactsField: "on-call"}},
+			NamespaceSelector:   &metav1.LabelSelector{MatchLabels: map[string]string{"environment": "production"}},
+		}},
+		&monitoringv1alpha1.ClusterMonitorTemplate{ObjectMeta: ctrl.ObjectMeta{Name: "standard-http"}, Spec: monitoringv1alpha1.ClusterMonitorTemplateSpec{
+			MonitorTemplateSpec: monitoringv1alpha1.MonitorTemplateSpec{Parameters: map[string]string{httputil.TypeField: "HTTP", monitorutil.Interval: "300"}},
+		}},
+		&monitoringv1alpha1.MonitorTemplate{ObjectMeta: ctrl.ObjectMeta{Name: "web", Namespace: "prod"}, Spec: monitoringv1alpha1.MonitorTemplateSpec{
+			Parameters: map[string]string{monitorutil.Interval: "120", monitorutil.Timeout: "30"},
+			Selector:   &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		}},
+		&monitoringv1alpha1.MonitorTemplate{ObjectMeta: ctrl.ObjectMeta{Name: "standard-http", Namespace: "prod"}, Spec: monitoringv1alpha1.MonitorTemplateSpec{
+			Parameters: map[string]string{httputil.TypeField: "HTTPS"},
+		}},
+	}
+}
+
+func TestTemplateResolver_Resolve(t *testing.T) {
+	templateKey := monitorutil.GetUptimeRobotMonitorPrefix() + monitorutil.TemplateAnnotation
+	tests := []struct {
+		name        string
+		namespace   string
+		labels      map[string]string
+		annotations map[string]string
+		want        map[string]string
+		wantErr     bool
+	}{
+		{name: "should resolve no parameters without matching template", namespace: "default", want: map[string]string{}},
+		{name: "should resolve cluster template matching the namespace", namespace: "prod", want: map[string]string{monitorutil.Interval: "60", httputil.AlertContactsField: "on-call"}},
+		{name: "should resolve namespaced template over cluster template", namespace: "prod", labels: map[string]string{"app": "web"}, want: map[string]string{monitorutil.Interval: "120", monitorutil.Timeout: "30", httputil.AlertContactsField: "on-call"}},
+		{name: "should resolve named cluster template", namespace: "default", annotations: map[string]string{templateKey: "standard-http"}, want: map[string]string{httputil.TypeField: "HTTP", monitorutil.Interval: "300"}},
+		{name: "should resolve named namespaced template over cluster template of the same name", namespace: "prod", annotations: map[string]string{templateKey: "standard-http"}, want: map[string]string{httputil.TypeField: "HTTPS", monitorutil.Interval: "60", httputil.AlertContactsField: "on-call"}},
+		{name: "should return error on missing named template", namespace: "default", annotations: map[string]string{templateKey: "missing"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &TemplateResolver{Client: fake.NewClientBuilder().WithScheme(newTemplateScheme(t)).WithObjects(newTemplateObjects()...).Build()}
+			got, err := r.Resolve(context.Background(), &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Name: "ingress", Namespace: tt.namespace, Labels: tt.labels, Annotations: tt.annotations}})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateResolver_uses(t *testing.T) {
+	templateKey := monitorutil.GetUptimeRobotMonitorPrefix() + monitorutil.TemplateAnnotation
+	templates := newTemplateObjects()
+	tests := []struct {
+		name     string
+		object   client.Object
+		template client.Object
+		want     bool
+	}{
+		{name: "should use named template", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "default", Annotations: map[string]string{templateKey: "standard-http"}}}, template: templates[3], want: true},
+		{name: "should not use named template of other namespace", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "default", Annotations: map[string]string{templateKey: "standard-http"}}}, template: templates[5], want: false},
+		{name: "should use template matching the labels", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "prod", Labels: map[string]string{"app": "web"}}}, template: templates[4], want: true},
+		{name: "should not use template not matching the labels", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "prod"}}, template: templates[4], want: false},
+		{name: "should use cluster template with namespace selector", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "default"}}, template: templates[2], want: true},
+		{name: "should not use cluster template without selector", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "default"}}, template: templates[3], want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &TemplateResolver{}
+			if got := r.uses(tt.object, tt.template); got != tt.want {
+				t.Errorf("uses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUptimerobotReconciler_Reconcile_template(t *testing.T) {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	ingress := &network.Ingress{
+		ObjectMeta: ctrl.ObjectMeta{Name: "ingress", Namespace: "prod", Annotations: map[string]string{
+			monitorutil.GetUptimeRobotDomain():         "true",
+			prefix + httputil.FriendlyNameField:        "tester",
+			prefix + monitorutil.TemplateAnnotation:    "standard-http",
+			prefix + monitorutil.Interval:              "600",
+			prefix + monitorutil.Interval + ".b.local": "900",
+		}},
+		Spec: network.IngressSpec{Rules: []network.IngressRule{{Host: "a.local"}}},
+	}
+	c := fake.NewClientBuilder().WithScheme(newTemplateScheme(t)).WithObjects(append(newTemplateObjects(), ingress)...).Build()
+
+	testutilprovider := &testUtilProvider{}
+	testutilprovider.On("CreateMonitor", "", "http://a.local", mock.MatchedBy(func(annotations map[string]string) bool {
+		return annotations[prefix+httputil.TypeField] == "HTTPS" &&
+			annotations[prefix+monitorutil.Interval] == "600" &&
+			annotations[prefix+httputil.AlertContactsField] == "on-call"
+	})).Return("1", nil)
+	defer testutilprovider.AssertExpectations(t)
+
+	r := &UptimerobotReconciler{HostMonitorSyncer: HostMonitorSyncer{
+		Client:           c,
+		Recorder:         record.NewFakeRecorder(100),
+		TemplateResolver: &TemplateResolver{Client: c},
+		UtilProvider:     testutilprovider,
+	}}
+	tn := types.NamespacedName{Namespace: "prod", Name: "ingress"}
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: tn}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := &network.Ingress{}
+	if err := c.Get(context.Background(), tn, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)] != monitorutil.StatusSynced {
+		t.Errorf("status = %v, want %v", got.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)], monitorutil.StatusSynced)
+	}
+	if got.Annotations[prefix+httputil.TypeField] != "" {
+		t.Errorf("template parameters written to the ingress annotations")
+	}
+}
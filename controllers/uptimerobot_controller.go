@@ -19,33 +19,27 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
-	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
-	"sigs.k8s.io/controller-runtime/pkg/builder"
-	"sigs.k8s.io/controller-runtime/pkg/event"
-	"sigs.k8s.io/controller-runtime/pkg/predicate"
-	"strconv"
+	"regexp"
+	"sort"
 
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	corev1 "k8s.io/api/core/v1"
 	network "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 type UptimerobotReconciler struct {
-	client.Client
+	HostMonitorSyncer
 	Scheme *runtime.Scheme
-	UtilProvider
-}
-
-type UtilProvider interface {
-	CreateMonitor(host string, annotations map[string]string) error
-	DeleteMonitor(host string, annotations map[string]string) error
 }
 
-// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;watch;list;
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;watch;list;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 func (r *UptimerobotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	nameSpacedName := req.NamespacedName
@@ -58,28 +52,27 @@ func (r *UptimerobotReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	hosts := buildHostSchemeMap(ingress)
-	for host, scheme := range hosts {
-		hostWithScheme := scheme + "://" + host
-		if err := r.UtilProvider.CreateMonitor(hostWithScheme, ingress.Annotations); err != nil {
-			log.Log.Error(err, fmt.Sprintf("Monitor %s not successfully created/updated", hostWithScheme))
-			return ctrl.Result{}, nil
-		}
-		log.Log.Info(fmt.Sprintf("Monitor %s successfully created/updated", hostWithScheme))
+	hosts, err := buildHostSchemeMap(ingress)
+	if err != nil {
+		log.Log.Error(err, fmt.Sprintf("Ingress %s/%s monitors not successfully built", ingress.Namespace, ingress.Name))
+		r.Recorder.Event(ingress, corev1.EventTypeWarning, MonitorSyncFailedReason, fmt.Sprintf("Ingress monitors not successfully built: %s", err))
+		return ctrl.Result{}, patchStatusAnnotations(ctx, r.Client, ingress, monitorutil.StatusFailed, nil, "")
 	}
-
-	return ctrl.Result{}, nil
+	return r.Sync(ctx, ingress, IngressKind, hosts)
 }
 
-func (r *UptimerobotReconciler) CreateMonitor(host string, annotations map[string]string) error {
-	return monitorutil.CreateMonitor(host, annotations)
-}
-
-func (r *UptimerobotReconciler) DeleteMonitor(host string, annotations map[string]string) error {
-	return monitorutil.DeleteMonitor(host, annotations)
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
-func buildHostSchemeMap(ingress *network.Ingress) map[string]string {
+// buildHostSchemeMap maps the hosts of the ingress to their scheme. With the paths annotation every path of the
+// rules of a host matching the annotation gets its own monitor instead, the host is not monitored when none match.
+func buildHostSchemeMap(ingress *network.Ingress) (map[string]string, error) {
 	hosts := map[string]string{}
 
 	for _, rule := range ingress.Spec.Rules {
@@ -92,74 +85,35 @@ func buildHostSchemeMap(ingress *network.Ingress) map[string]string {
 			}
 		}
 	}
-	return hosts
-}
 
-func (r *UptimerobotReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&network.Ingress{}, builder.WithPredicates(r.FilterEnabledIngress())).
-		Complete(r)
-}
-
-func (r *UptimerobotReconciler) FilterEnabledIngress() predicate.Predicate {
-	return predicate.Funcs{CreateFunc: func(event event.CreateEvent) bool {
-		return r.filterCreateEvent(event)
-	}, UpdateFunc: func(updateEvent event.UpdateEvent) bool {
-		return r.filterUpdateEvent(updateEvent)
-	}, DeleteFunc: func(deleteEvent event.DeleteEvent) bool {
-		return r.filterDeleteEvent(deleteEvent)
-	}, GenericFunc: func(genericEvent event.GenericEvent) bool {
-		return r.filterGenericEvent(genericEvent)
-	}}
-}
-
-func (r *UptimerobotReconciler) filterGenericEvent(genericEvent event.GenericEvent) bool {
-	if genericEvent.Object != nil {
-		return r.hasEnabledUptimeRobotMonitor(genericEvent.Object.GetAnnotations())
-	}
-	return false
-}
-
-func (r *UptimerobotReconciler) filterDeleteEvent(deleteEvent event.DeleteEvent) bool {
-	if deleteEvent.Object != nil {
-		enabled := r.hasEnabledUptimeRobotMonitor(deleteEvent.Object.GetAnnotations())
-		if enabled {
-			go r.cleanUpAfterIngressDeletion(deleteEvent.Object.GetAnnotations())
+	pathsAnnotation := monitorutil.GetUptimeRobotMonitorPrefix() + monitorutil.PathsAnnotation
+	targets := map[string]string{}
+	for host, scheme := range hosts {
+		pattern, exists := monitorutil.GetHostAnnotations(ingress.Annotations, host)[pathsAnnotation]
+		if !exists {
+			targets[host] = scheme
+			continue
 		}
-	}
-	return false
-}
-
-func (r *UptimerobotReconciler) filterUpdateEvent(updateEvent event.UpdateEvent) bool {
-	if updateEvent.ObjectNew != nil {
-		return r.hasEnabledUptimeRobotMonitor(updateEvent.ObjectNew.GetAnnotations())
-	}
-	return false
-}
-
-func (r *UptimerobotReconciler) filterCreateEvent(event event.CreateEvent) bool {
-	if event.Object != nil {
-		return r.hasEnabledUptimeRobotMonitor(event.Object.GetAnnotations())
-	}
-	return false
-}
-
-func (r *UptimerobotReconciler) cleanUpAfterIngressDeletion(annotations map[string]string) {
-	err := r.UtilProvider.DeleteMonitor("", annotations)
-	if err != nil {
-		log.Log.Error(err, fmt.Sprintf("Monitor %s not successfully deleted", annotations[monitorutil.GetUptimeRobotMonitorPrefix()+httputil.FriendlyNameField]))
-	} else {
-		log.Log.Info(fmt.Sprintf("Monitor %s successfully deleted", annotations[monitorutil.GetUptimeRobotMonitorPrefix()+httputil.FriendlyNameField]))
-	}
-}
-
-func (r *UptimerobotReconciler) hasEnabledUptimeRobotMonitor(annotationMap map[string]string) bool {
-	if val, exists := annotationMap[monitorutil.GetUptimeRobotDomain()]; exists {
-		isEnabled, err := strconv.ParseBool(val)
+		paths, err := regexp.Compile(pattern)
 		if err != nil {
-			return false
+			return nil, fmt.Errorf("invalid %s annotation %q: %w", pathsAnnotation, pattern, err)
+		}
+		for _, rule := range ingress.Spec.Rules {
+			if rule.Host != host || rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				if paths.MatchString(path.Path) {
+					targets[host+path.Path] = scheme
+				}
+			}
 		}
-		return isEnabled
 	}
-	return false
+	return targets, nil
+}
+
+func (r *UptimerobotReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return r.WatchDependencies(ctrl.NewControllerManagedBy(mgr).
+		For(&network.Ingress{}, builder.WithPredicates(r.FilterEnabled())), func() client.ObjectList { return &network.IngressList{} }).
+		Complete(r)
 }
@@ -4,16 +4,27 @@ import (
 	"context"
 	"errors"
 	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/service/monitor"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
 	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
 	network "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"net"
+	"net/http"
+	"net/url"
 	"reflect"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
-	"sync"
 	"testing"
+	"time"
 )
 
 type testClient struct {
@@ -22,33 +33,118 @@ type testClient struct {
 }
 
 type testUtilProvider struct {
-	wg sync.WaitGroup
 	mock.Mock
 }
 
 func (t *testClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
-	if key.Name == "ValidIngress" {
+	switch key.Name {
+	case "ValidIngress", "NewIngress", "DeletedIngress", "SyncedIngress":
 		ingress := obj.(*network.Ingress)
 		ingress.Annotations = map[string]string{
 			monitorutil.GetUptimeRobotDomain(): "true",
 		}
 		ingress.Spec.Rules = []network.IngressRule{{Host: "test.localhost"}}
+		if key.Name == "SyncedIngress" {
+			ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)] = monitorutil.StatusSynced
+			hosts, _ := buildHostSchemeMap(ingress)
+			ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.LastAppliedAnnotation)] = desiredStateHash(ingress.Annotations, hosts, ingress.Labels)
+		}
+		if key.Name != "NewIngress" {
+			ingress.Finalizers = []string{monitorutil.GetUptimeRobotFinalizer()}
+		}
+		if key.Name == "DeletedIngress" {
+			now := metav1.Now()
+			ingress.DeletionTimestamp = &now
+		}
+	case "MultiHostIngress", "DeletedMultiHostIngress":
+		ingress := obj.(*network.Ingress)
+		prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+		ingress.Name = key.Name
+		ingress.Namespace = key.Namespace
+		ingress.Annotations = map[string]string{
+			monitorutil.GetUptimeRobotDomain():                   "true",
+			prefix + httputil.FriendlyNameField:                  "tester",
+			prefix + httputil.FriendlyNameField + ".b.localhost": "custom",
+			prefix + monitorutil.Interval + ".a.localhost":       "60",
+		}
+		ingress.Spec.Rules = []network.IngressRule{{Host: "a.localhost"}, {Host: "b.localhost"}}
+		ingress.Finalizers = []string{monitorutil.GetUptimeRobotFinalizer()}
+		if key.Name == "DeletedMultiHostIngress" {
+			now := metav1.Now()
+			ingress.DeletionTimestamp = &now
+		}
 	}
 	args := t.Called(ctx, key, obj, opts)
 	return args.Error(0)
 }
 
-func (r *testUtilProvider) CreateMonitor(host string, annotations map[string]string) error {
-	args := r.Called(host, annotations)
+func (t *testClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	args := t.Called(ctx, obj, opts)
+	return args.Error(0)
+}
+
+func (t *testClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	args := t.Called(ctx, obj, patch, opts)
+	return args.Error(0)
+}
+
+func (r *testUtilProvider) CreateMonitor(_ context.Context, apiKey string, host string, annotations map[string]string) (string, error) {
+	args := r.Called(apiKey, host, annotations)
+	return args.String(0), args.Error(1)
+}
+
+func (r *testUtilProvider) UpdateMonitor(_ context.Context, apiKey string, id string, host string, annotations map[string]string) (string, error) {
+	args := r.Called(apiKey, id, host, annotations)
+	return args.String(0), args.Error(1)
+}
+
+func (r *testUtilProvider) DeleteMonitor(_ context.Context, apiKey string, host string, annotations map[string]string) error {
+	args := r.Called(apiKey, host, annotations)
+	return args.Error(0)
+}
+
+func (r *testUtilProvider) GetMonitorDrift(_ context.Context, apiKey string, host string, annotations map[string]string) (*monitorutil.Drift, error) {
+	args := r.Called(apiKey, host, annotations)
+	drift, _ := args.Get(0).(*monitorutil.Drift)
+	return drift, args.Error(1)
+}
+
+// matchHostAnnotations matches the annotations of the monitor of a host of MultiHostIngress.
+func matchHostAnnotations(friendlyName string, interval string) interface{} {
+	return mock.MatchedBy(func(annotations map[string]string) bool {
+		prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+		return annotations[prefix+httputil.FriendlyNameField] == friendlyName && annotations[prefix+monitorutil.Interval] == interval
+	})
+}
+
+func (r *testUtilProvider) ListMonitors(_ context.Context, apiKey string, search string) ([]monitorutil.Monitor, error) {
+	args := r.Called(apiKey, search)
+	monitors, _ := args.Get(0).([]monitorutil.Monitor)
+	return monitors, args.Error(1)
+}
+
+func (r *testUtilProvider) DeleteMonitorById(_ context.Context, apiKey string, id string) error {
+	args := r.Called(apiKey, id)
 	return args.Error(0)
 }
 
-func (r *testUtilProvider) DeleteMonitor(host string, annotations map[string]string) error {
-	args := r.Called(host, annotations)
-	r.wg.Done()
+func (r *testUtilProvider) SetMonitorPausedById(_ context.Context, apiKey string, id string, paused bool) error {
+	args := r.Called(apiKey, id, paused)
 	return args.Error(0)
 }
 
+func (r *testUtilProvider) FindMonitorToAdopt(_ context.Context, apiKey string, id string, host string, annotations map[string]string) (*monitorutil.Drift, error) {
+	args := r.Called(apiKey, id, host, annotations)
+	drift, _ := args.Get(0).(*monitorutil.Drift)
+	return drift, args.Error(1)
+}
+
+func (r *testUtilProvider) GetMonitorStatuses(_ context.Context, apiKey string, ids []string) ([]monitorutil.MonitorStatus, error) {
+	args := r.Called(apiKey, ids)
+	statuses, _ := args.Get(0).([]monitorutil.MonitorStatus)
+	return statuses, args.Error(1)
+}
+
 func TestUptimerobotReconciler_Reconcile(t *testing.T) {
 	type args struct {
 		host        string
@@ -57,7 +153,8 @@ func TestUptimerobotReconciler_Reconcile(t *testing.T) {
 	var testclient *testClient
 	var testutilprovider *testUtilProvider
 	r := &UptimerobotReconciler{
-		Scheme: &runtime.Scheme{},
+		HostMonitorSyncer: HostMonitorSyncer{Recorder: record.NewFakeRecorder(100)},
+		Scheme:            &runtime.Scheme{},
 	}
 	tests := []struct {
 		name        string
@@ -77,10 +174,73 @@ func TestUptimerobotReconciler_Reconcile(t *testing.T) {
 		{name: "should return nil when create monitor action fails with valid ingress", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
 			testclient = &testClient{}
 			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{Namespace: "default", Name: "ValidIngress"}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Patch", mock.IsType(context.Background()), mock.MatchedBy(func(ingress *network.Ingress) bool {
+				return ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)] == monitorutil.StatusFailed
+			}), mock.Anything, mock.Anything).Return(nil)
 			r.Client = testclient
 
 			testutilprovider = &testUtilProvider{}
-			testutilprovider.On("CreateMonitor", mock.Anything, mock.IsType(map[string]string{})).Return(errors.New("some error"))
+			testutilprovider.On("CreateMonitor", "", mock.Anything, mock.IsType(map[string]string{})).Return("", errors.New("some error"))
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "ValidIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testutilprovider.AssertExpectations(t)
+		}, wantError: false},
+		{name: "should return err to requeue when create monitor action fails with server error", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{Namespace: "default", Name: "ValidIngress"}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Patch", mock.IsType(context.Background()), mock.MatchedBy(func(ingress *network.Ingress) bool {
+				return ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)] == monitorutil.StatusFailed
+			}), mock.Anything, mock.Anything).Return(nil)
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("CreateMonitor", "", mock.Anything, mock.IsType(map[string]string{})).Return("", &monitorutil.APIError{StatusCode: http.StatusServiceUnavailable})
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "ValidIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testutilprovider.AssertExpectations(t)
+		}, wantError: true},
+		{name: "should return err to requeue when create monitor action is rate limited", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{Namespace: "default", Name: "ValidIngress"}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Patch", mock.IsType(context.Background()), mock.MatchedBy(func(ingress *network.Ingress) bool {
+				return ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)] == monitorutil.StatusFailed
+			}), mock.Anything, mock.Anything).Return(nil)
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("CreateMonitor", "", mock.Anything, mock.IsType(map[string]string{})).Return("", &monitorutil.APIError{StatusCode: http.StatusTooManyRequests})
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "ValidIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testutilprovider.AssertExpectations(t)
+		}, wantError: true},
+		{name: "should return err to requeue when create monitor action fails with network error", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{Namespace: "default", Name: "ValidIngress"}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Patch", mock.IsType(context.Background()), mock.MatchedBy(func(ingress *network.Ingress) bool {
+				return ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)] == monitorutil.StatusFailed
+			}), mock.Anything, mock.Anything).Return(nil)
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("CreateMonitor", "", mock.Anything, mock.IsType(map[string]string{})).Return("", &url.Error{Op: "Post", URL: "https://api.uptimerobot.com/v2/editMonitor", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}})
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "ValidIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testutilprovider.AssertExpectations(t)
+		}, wantError: true},
+		{name: "should return nil when create monitor action fails with client error", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{Namespace: "default", Name: "ValidIngress"}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Patch", mock.IsType(context.Background()), mock.MatchedBy(func(ingress *network.Ingress) bool {
+				return ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)] == monitorutil.StatusFailed
+			}), mock.Anything, mock.Anything).Return(nil)
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("CreateMonitor", "", mock.Anything, mock.IsType(map[string]string{})).Return("", &monitorutil.APIError{StatusCode: http.StatusBadRequest})
 			r.UtilProvider = testutilprovider
 		}, tn: types.NamespacedName{Namespace: "default", Name: "ValidIngress"}, verifyMocks: func() {
 			testclient.AssertExpectations(t)
@@ -89,15 +249,168 @@ func TestUptimerobotReconciler_Reconcile(t *testing.T) {
 		{name: "should return nil when create monitor action is successful with valid ingress", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
 			testclient = &testClient{}
 			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{Namespace: "default", Name: "ValidIngress"}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Patch", mock.IsType(context.Background()), mock.MatchedBy(func(ingress *network.Ingress) bool {
+				return ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)] == monitorutil.StatusSynced &&
+					ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)] == "1"
+			}), mock.Anything, mock.Anything).Return(nil)
 			r.Client = testclient
 
 			testutilprovider = &testUtilProvider{}
-			testutilprovider.On("CreateMonitor", mock.Anything, mock.IsType(map[string]string{})).Return(nil)
+			testutilprovider.On("CreateMonitor", "", mock.Anything, mock.IsType(map[string]string{})).Return("1", nil)
 			r.UtilProvider = testutilprovider
 		}, tn: types.NamespacedName{Namespace: "default", Name: "ValidIngress"}, verifyMocks: func() {
 			testclient.AssertExpectations(t)
 			testutilprovider.AssertExpectations(t)
 		}, wantError: false},
+		{name: "should not apply synced monitor without drift", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Patch", mock.IsType(context.Background()), mock.MatchedBy(func(ingress *network.Ingress) bool {
+				return ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)] == "1"
+			}), mock.Anything, mock.Anything).Return(nil)
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("GetMonitorDrift", "", "http://test.localhost", mock.IsType(map[string]string{})).Return(&monitorutil.Drift{MonitorId: "1", Fields: []string{}}, nil)
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "SyncedIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testutilprovider.AssertExpectations(t)
+			testutilprovider.AssertNotCalled(t, "CreateMonitor", mock.Anything, mock.Anything, mock.Anything)
+		}, wantError: false},
+		{name: "should apply synced monitor again when it drifted", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Patch", mock.IsType(context.Background()), mock.IsType(&network.Ingress{}), mock.Anything, mock.Anything).Return(nil)
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("GetMonitorDrift", "", "http://test.localhost", mock.IsType(map[string]string{})).Return(&monitorutil.Drift{MonitorId: "1", Fields: []string{"interval"}}, nil)
+			testutilprovider.On("CreateMonitor", "", "http://test.localhost", mock.IsType(map[string]string{})).Return("1", nil)
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "SyncedIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testutilprovider.AssertExpectations(t)
+		}, wantError: false},
+		{name: "should apply synced monitor again when it is missing", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Patch", mock.IsType(context.Background()), mock.IsType(&network.Ingress{}), mock.Anything, mock.Anything).Return(nil)
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("GetMonitorDrift", "", "http://test.localhost", mock.IsType(map[string]string{})).Return(&monitorutil.Drift{Missing: true}, nil)
+			testutilprovider.On("CreateMonitor", "", "http://test.localhost", mock.IsType(map[string]string{})).Return("2", nil)
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "SyncedIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testutilprovider.AssertExpectations(t)
+		}, wantError: false},
+		{name: "should add finalizer before creating monitor", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Update", mock.IsType(context.Background()), mock.MatchedBy(func(ingress *network.Ingress) bool {
+				return controllerutil.ContainsFinalizer(ingress, monitorutil.GetUptimeRobotFinalizer())
+			}), mock.Anything).Return(nil)
+			testclient.On("Patch", mock.IsType(context.Background()), mock.MatchedBy(func(ingress *network.Ingress) bool {
+				return ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation)] == monitorutil.StatusSynced &&
+					ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)] == "1"
+			}), mock.Anything, mock.Anything).Return(nil)
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("CreateMonitor", "", mock.Anything, mock.IsType(map[string]string{})).Return("1", nil)
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "NewIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testutilprovider.AssertExpectations(t)
+		}, wantError: false},
+		{name: "should create a monitor with its own friendly name for every host", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Patch", mock.IsType(context.Background()), mock.MatchedBy(func(ingress *network.Ingress) bool {
+				return ingress.Annotations[monitorutil.GetStatusAnnotationKey(monitorutil.IdAnnotation)] == "1,2"
+			}), mock.Anything, mock.Anything).Return(nil)
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("CreateMonitor", "", "http://a.localhost", matchHostAnnotations("tester-a.localhost", "60")).Return("1", nil)
+			testutilprovider.On("CreateMonitor", "", "http://b.localhost", matchHostAnnotations("custom", "")).Return("2", nil)
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "MultiHostIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testutilprovider.AssertExpectations(t)
+		}, wantError: false},
+		{name: "should delete the monitor of every host", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Update", mock.IsType(context.Background()), mock.MatchedBy(func(ingress *network.Ingress) bool {
+				return !controllerutil.ContainsFinalizer(ingress, monitorutil.GetUptimeRobotFinalizer())
+			}), mock.Anything).Return(nil)
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("DeleteMonitor", "", "http://a.localhost", matchHostAnnotations("tester-a.localhost", "60")).Return(nil)
+			testutilprovider.On("DeleteMonitor", "", "http://b.localhost", matchHostAnnotations("custom", "")).Return(errors.New(monitor.MsgMonitorDoesNotExist))
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "DeletedMultiHostIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testutilprovider.AssertExpectations(t)
+		}, wantError: false},
+		{name: "should return err to requeue when status cannot be recorded", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{Namespace: "default", Name: "ValidIngress"}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Patch", mock.IsType(context.Background()), mock.IsType(&network.Ingress{}), mock.Anything, mock.Anything).Return(errors.New("some error"))
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("CreateMonitor", "", mock.Anything, mock.IsType(map[string]string{})).Return("", errors.New("some error"))
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "ValidIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testutilprovider.AssertExpectations(t)
+		}, wantError: true},
+		{name: "should return err and keep finalizer when monitor deletion fails", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("DeleteMonitor", "", mock.IsType(""), mock.IsType(map[string]string{})).Return(errors.New("some error"))
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "DeletedIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testclient.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+			testutilprovider.AssertExpectations(t)
+		}, wantError: true},
+		{name: "should remove finalizer when monitor is deleted", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Update", mock.IsType(context.Background()), mock.MatchedBy(func(ingress *network.Ingress) bool {
+				return !controllerutil.ContainsFinalizer(ingress, monitorutil.GetUptimeRobotFinalizer())
+			}), mock.Anything).Return(nil)
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("DeleteMonitor", "", mock.IsType(""), mock.IsType(map[string]string{})).Return(nil)
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "DeletedIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testutilprovider.AssertExpectations(t)
+		}, wantError: false},
+		{name: "should remove finalizer when monitor no longer exists", args: args{host: "", annotations: map[string]string{}}, setupMocks: func() {
+			testclient = &testClient{}
+			testclient.On("Get", mock.IsType(context.Background()), mock.IsType(types.NamespacedName{}), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			testclient.On("Update", mock.IsType(context.Background()), mock.IsType(&network.Ingress{}), mock.Anything).Return(nil)
+			r.Client = testclient
+
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("DeleteMonitor", "", mock.IsType(""), mock.IsType(map[string]string{})).Return(errors.New(monitor.MsgMonitorDoesNotExist))
+			r.UtilProvider = testutilprovider
+		}, tn: types.NamespacedName{Namespace: "default", Name: "DeletedIngress"}, verifyMocks: func() {
+			testclient.AssertExpectations(t)
+			testutilprovider.AssertExpectations(t)
+		}, wantError: false},
 	}
 
 	for _, tt := range tests {
@@ -152,14 +465,57 @@ func Test_hasEnabledUptimeRobotMonitor(t *testing.T) {
 		})
 	}
 }
+func Test_isManaged(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: ctrl.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: ctrl.ObjectMeta{Name: "tenant", Labels: map[string]string{"uptimerobot": "enabled"}}},
+	).Build()
+	enabled := map[string]string{monitorutil.GetUptimeRobotDomain(): "true"}
+	webSelector := labels.SelectorFromSet(labels.Set{"app": "web"})
+	tenantSelector := labels.SelectorFromSet(labels.Set{"uptimerobot": "enabled"})
+	tests := []struct {
+		name               string
+		labelSelector      labels.Selector
+		namespaceSelector  labels.Selector
+		ingressClassFilter *IngressClassFilter
+		object             client.Object
+		want               bool
+	}{
+		{name: "should manage enabled ingress without selectors", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "default", Annotations: enabled}}, want: true},
+		{name: "should not manage ingress not enabled", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "tenant", Labels: map[string]string{"app": "web"}}}, labelSelector: webSelector, namespaceSelector: tenantSelector, want: false},
+		{name: "should manage ingress matching the label selector", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "default", Labels: map[string]string{"app": "web"}, Annotations: enabled}}, labelSelector: webSelector, want: true},
+		{name: "should not manage ingress not matching the label selector", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "default", Annotations: enabled}}, labelSelector: webSelector, want: false},
+		{name: "should manage ingress of namespace matching the namespace selector", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "tenant", Annotations: enabled}}, namespaceSelector: tenantSelector, want: true},
+		{name: "should not manage ingress of namespace not matching the namespace selector", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "default", Annotations: enabled}}, namespaceSelector: tenantSelector, want: false},
+		{name: "should not manage ingress of missing namespace", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "missing", Annotations: enabled}}, namespaceSelector: tenantSelector, want: false},
+		{name: "should not manage enabled ingress of class not admitted", object: newClassIngress("internal", ""), ingressClassFilter: &IngressClassFilter{Denied: []string{"internal"}}, want: false},
+		{name: "should manage ingress of auto enabled class", object: newClassIngress("public", ""), ingressClassFilter: &IngressClassFilter{AutoEnabled: []string{"public"}}, want: true},
+		{name: "should not manage ingress of auto enabled class disabled by annotation", object: &network.Ingress{ObjectMeta: ctrl.ObjectMeta{Namespace: "default", Annotations: map[string]string{monitorutil.GetUptimeRobotDomain(): "false", IngressClassAnnotation: "public"}}}, ingressClassFilter: &IngressClassFilter{AutoEnabled: []string{"public"}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &UptimerobotReconciler{HostMonitorSyncer: HostMonitorSyncer{Client: c, LabelSelector: tt.labelSelector, NamespaceSelector: tt.namespaceSelector, IngressClassFilter: tt.ingressClassFilter}}
+			if got := r.isManaged(tt.object); got != tt.want {
+				t.Errorf("isManaged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_buildHostSchemeMap(t *testing.T) {
 	type args struct {
 		ingress *network.Ingress
 	}
+	pathsAnnotation := monitorutil.GetUptimeRobotMonitorPrefix() + monitorutil.PathsAnnotation
+	rules := []network.IngressRule{
+		{Host: "a.localhost", IngressRuleValue: network.IngressRuleValue{HTTP: &network.HTTPIngressRuleValue{Paths: []network.HTTPIngressPath{{Path: "/"}, {Path: "/healthz"}, {Path: "/api/healthz"}}}}},
+		{Host: "b.localhost", IngressRuleValue: network.IngressRuleValue{HTTP: &network.HTTPIngressRuleValue{Paths: []network.HTTPIngressPath{{Path: "/"}}}}},
+	}
 	tests := []struct {
-		name string
-		args args
-		want map[string]string
+		name    string
+		args    args
+		want    map[string]string
+		wantErr bool
 	}{
 		{name: "should return empty map", args: args{ingress: &network.Ingress{}}, want: map[string]string{}},
 		{name: "should return non empty map", args: args{ingress: &network.Ingress{Spec: network.IngressSpec{
@@ -172,10 +528,32 @@ func Test_buildHostSchemeMap(t *testing.T) {
 			TLS:   []network.IngressTLS{{Hosts: []string{"test.localhost"}}}}}}, want: map[string]string{
 			"test.localhost": "https",
 		}},
+		{name: "should return a monitor per matching path", args: args{ingress: &network.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{pathsAnnotation: "healthz$"}},
+			Spec:       network.IngressSpec{Rules: rules},
+		}}, want: map[string]string{
+			"a.localhost/healthz":     "http",
+			"a.localhost/api/healthz": "http",
+		}},
+		{name: "should return paths of host overridden annotation", args: args{ingress: &network.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{pathsAnnotation: "^/healthz$", pathsAnnotation + ".b.localhost": ".*"}},
+			Spec:       network.IngressSpec{Rules: rules},
+		}}, want: map[string]string{
+			"a.localhost/healthz": "http",
+			"b.localhost/":        "http",
+		}},
+		{name: "should return error on invalid paths annotation", args: args{ingress: &network.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{pathsAnnotation: "("}},
+			Spec:       network.IngressSpec{Rules: rules},
+		}}, wantErr: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := buildHostSchemeMap(tt.args.ingress); !reflect.DeepEqual(got, tt.want) {
+			got, err := buildHostSchemeMap(tt.args.ingress)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildHostSchemeMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("buildHostSchemeMap() = %v, want %v", got, tt.want)
 			}
 		})
@@ -301,6 +679,113 @@ func Test_filterUpdateEvent(t *testing.T) {
 				Kind: "Ingress",
 			},
 		}}}, want: false},
+		{name: "should return false if only status annotations changed", args: args{updateEvent: event.UpdateEvent{ObjectOld: &network.Ingress{
+			ObjectMeta: ctrl.ObjectMeta{
+				Name:      "Ingress",
+				Namespace: "default",
+				Annotations: map[string]string{
+					monitorutil.GetUptimeRobotDomain(): "true",
+				},
+			},
+		}, ObjectNew: &network.Ingress{
+			ObjectMeta: ctrl.ObjectMeta{
+				Name:      "Ingress",
+				Namespace: "default",
+				Annotations: map[string]string{
+					monitorutil.GetUptimeRobotDomain():                                   "true",
+					monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation):     monitorutil.StatusSynced,
+					monitorutil.GetStatusAnnotationKey(monitorutil.LastSyncedAnnotation): "2022-11-24T19:30:43Z",
+				},
+			},
+		}}}, want: false},
+		{name: "should return true if monitor annotations changed", args: args{updateEvent: event.UpdateEvent{ObjectOld: &network.Ingress{
+			ObjectMeta: ctrl.ObjectMeta{
+				Name:      "Ingress",
+				Namespace: "default",
+				Annotations: map[string]string{
+					monitorutil.GetUptimeRobotDomain(): "true",
+				},
+			},
+		}, ObjectNew: &network.Ingress{
+			ObjectMeta: ctrl.ObjectMeta{
+				Name:      "Ingress",
+				Namespace: "default",
+				Annotations: map[string]string{
+					monitorutil.GetUptimeRobotDomain():                     "true",
+					monitorutil.GetUptimeRobotMonitorPrefix() + "interval": "300",
+				},
+			},
+		}}}, want: true},
+		{name: "should return true if disabled ingress is pending clean up", args: args{updateEvent: event.UpdateEvent{ObjectNew: &network.Ingress{
+			ObjectMeta: ctrl.ObjectMeta{
+				Name:              "Ingress",
+				Namespace:         "default",
+				DeletionTimestamp: &metav1.Time{Time: time.Now()},
+				Finalizers:        []string{monitorutil.GetUptimeRobotFinalizer()},
+				Annotations: map[string]string{
+					monitorutil.GetUptimeRobotDomain(): "false",
+				},
+			},
+			TypeMeta: ctrl.TypeMeta{
+				Kind: "Ingress",
+			},
+		}}}, want: true},
+		{name: "should return true if ingress with synced monitors is disabled", args: args{updateEvent: event.UpdateEvent{ObjectOld: &network.Ingress{
+			ObjectMeta: ctrl.ObjectMeta{
+				Name:       "Ingress",
+				Namespace:  "default",
+				Finalizers: []string{monitorutil.GetUptimeRobotFinalizer()},
+				Annotations: map[string]string{
+					monitorutil.GetUptimeRobotDomain(): "true",
+				},
+			},
+		}, ObjectNew: &network.Ingress{
+			ObjectMeta: ctrl.ObjectMeta{
+				Name:        "Ingress",
+				Namespace:   "default",
+				Finalizers:  []string{monitorutil.GetUptimeRobotFinalizer()},
+				Annotations: map[string]string{},
+			},
+		}}}, want: true},
+		{name: "should return false if ingress without synced monitors is disabled", args: args{updateEvent: event.UpdateEvent{ObjectOld: &network.Ingress{
+			ObjectMeta: ctrl.ObjectMeta{
+				Name:      "Ingress",
+				Namespace: "default",
+				Annotations: map[string]string{
+					monitorutil.GetUptimeRobotDomain(): "true",
+				},
+			},
+		}, ObjectNew: &network.Ingress{
+			ObjectMeta: ctrl.ObjectMeta{
+				Name:      "Ingress",
+				Namespace: "default",
+				Annotations: map[string]string{
+					monitorutil.GetUptimeRobotDomain(): "false",
+				},
+			},
+		}}}, want: false},
+		{name: "should return false if disabled ingress with paused monitors is updated", args: args{updateEvent: event.UpdateEvent{ObjectOld: &network.Ingress{
+			ObjectMeta: ctrl.ObjectMeta{
+				Name:       "Ingress",
+				Namespace:  "default",
+				Finalizers: []string{monitorutil.GetUptimeRobotFinalizer()},
+				Annotations: map[string]string{
+					monitorutil.GetUptimeRobotDomain():                               "false",
+					monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation): monitorutil.StatusPaused,
+				},
+			},
+		}, ObjectNew: &network.Ingress{
+			ObjectMeta: ctrl.ObjectMeta{
+				Name:       "Ingress",
+				Namespace:  "default",
+				Finalizers: []string{monitorutil.GetUptimeRobotFinalizer()},
+				Labels:     map[string]string{"app": "web"},
+				Annotations: map[string]string{
+					monitorutil.GetUptimeRobotDomain():                               "false",
+					monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation): monitorutil.StatusPaused,
+				},
+			},
+		}}}, want: false},
 		{name: "should return true if ingress is enabled", args: args{updateEvent: event.UpdateEvent{ObjectNew: &network.Ingress{
 			ObjectMeta: ctrl.ObjectMeta{
 				Name:      "Ingress",
@@ -323,7 +808,7 @@ func Test_filterUpdateEvent(t *testing.T) {
 	}
 }
 
-func TestUptimerobotReconciler_cleanUpAfterIngressDeletion(t *testing.T) {
+func Test_filterDeleteEvent(t *testing.T) {
 	var testutilprovider *testUtilProvider
 	r := &UptimerobotReconciler{
 		Scheme: &runtime.Scheme{},
@@ -333,13 +818,11 @@ func TestUptimerobotReconciler_cleanUpAfterIngressDeletion(t *testing.T) {
 		deleteEvent event.DeleteEvent
 	}
 	tests := []struct {
-		name        string
-		args        args
-		want        bool
-		setupMocks  func()
-		verifyMocks func()
+		name string
+		args args
+		want bool
 	}{
-		{name: "should return false and call delete monitor if ingress resource enabled and return error", args: args{deleteEvent: event.DeleteEvent{Object: &network.Ingress{
+		{name: "should return false and not call delete monitor if ingress resource enabled", args: args{deleteEvent: event.DeleteEvent{Object: &network.Ingress{
 			ObjectMeta: ctrl.ObjectMeta{
 				Name:      "Ingress",
 				Namespace: "default",
@@ -350,33 +833,7 @@ func TestUptimerobotReconciler_cleanUpAfterIngressDeletion(t *testing.T) {
 			TypeMeta: ctrl.TypeMeta{
 				Kind: "Ingress",
 			},
-		}}}, setupMocks: func() {
-			testutilprovider = &testUtilProvider{}
-			testutilprovider.wg.Add(1)
-			testutilprovider.On("DeleteMonitor", mock.IsType(""), mock.IsType(map[string]string{})).Return(errors.New(""))
-			r.UtilProvider = testutilprovider
-		}, verifyMocks: func() {
-			testutilprovider.AssertExpectations(t)
-		}, want: false},
-		{name: "should return false and call delete monitor if ingress resource enabled and return nil", args: args{deleteEvent: event.DeleteEvent{Object: &network.Ingress{
-			ObjectMeta: ctrl.ObjectMeta{
-				Name:      "Ingress",
-				Namespace: "default",
-				Annotations: map[string]string{
-					monitorutil.GetUptimeRobotDomain(): "true",
-				},
-			},
-			TypeMeta: ctrl.TypeMeta{
-				Kind: "Ingress",
-			},
-		}}}, setupMocks: func() {
-			testutilprovider = &testUtilProvider{}
-			testutilprovider.wg.Add(1)
-			testutilprovider.On("DeleteMonitor", mock.IsType(""), mock.IsType(map[string]string{})).Return(nil)
-			r.UtilProvider = testutilprovider
-		}, verifyMocks: func() {
-			testutilprovider.AssertExpectations(t)
-		}, want: false},
+		}}}, want: false},
 		{name: "should return false and not call delete monitor if ingress resource disabled", args: args{deleteEvent: event.DeleteEvent{Object: &network.Ingress{
 			ObjectMeta: ctrl.ObjectMeta{
 				Name:      "Ingress",
@@ -388,23 +845,16 @@ func TestUptimerobotReconciler_cleanUpAfterIngressDeletion(t *testing.T) {
 			TypeMeta: ctrl.TypeMeta{
 				Kind: "Ingress",
 			},
-		}}}, setupMocks: func() {
-			testutilprovider = &testUtilProvider{}
-			testutilprovider.On("DeleteMonitor", mock.IsType(""), mock.IsType(map[string]string{})).Return(errors.New(""))
-			r.UtilProvider = testutilprovider
-		}, verifyMocks: func() {
-			testutilprovider.AssertNotCalled(t, "DeleteMonitor", mock.IsType(""), mock.IsType(map[string]string{}))
-		}, want: false},
+		}}}, want: false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.setupMocks()
-			defer tt.verifyMocks()
-			got := r.filterDeleteEvent(tt.args.deleteEvent)
-			testutilprovider.wg.Wait()
-			if got != tt.want {
+			testutilprovider = &testUtilProvider{}
+			r.UtilProvider = testutilprovider
+			if got := r.filterDeleteEvent(tt.args.deleteEvent); got != tt.want {
 				t.Errorf("filterDeleteEvent() = %v, want %v", got, tt.want)
 			}
+			testutilprovider.AssertNotCalled(t, "DeleteMonitor", mock.Anything, mock.Anything, mock.Anything)
 		})
 	}
 }
@@ -0,0 +1,250 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	monitoringv1alpha1 "github.com/bennsimon/uptimerobot-operator/api/v1alpha1"
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/service/monitor"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// UptimeRobotMonitorReconciler reconciles a UptimeRobotMonitor object
+type UptimeRobotMonitorReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// ResyncPeriod is the period after which synced monitors are checked for drift, zero disables it.
+	ResyncPeriod time.Duration
+	// ClusterId marks the friendly names of the monitors with their resource so that orphans can be garbage
+	// collected, monitors are not marked when empty.
+	ClusterId string
+	// APIKeyResolver resolves the UptimeRobot account of each resource, the UPTIME_ROBOT_API_KEY env var is used
+	// for every resource when nil.
+	APIKeyResolver *APIKeyResolver
+	UtilProvider
+}
+
+//+kubebuilder:rbac:groups=monitoring.bennsimon.github.io,resources=uptimerobotmonitors,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=monitoring.bennsimon.github.io,resources=uptimerobotmonitors/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=monitoring.bennsimon.github.io,resources=uptimerobotmonitors/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *UptimeRobotMonitorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	uptimeRobotMonitor := &monitoringv1alpha1.UptimeRobotMonitor{}
+	if err := r.Get(ctx, req.NamespacedName, uptimeRobotMonitor); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	annotations := buildAnnotationsFromSpec(&uptimeRobotMonitor.Spec)
+	if len(r.ClusterId) > 0 {
+		annotations[monitorutil.GetUptimeRobotMonitorPrefix()+httputil.FriendlyNameField] += monitorutil.GetOwnerMarker(r.ClusterId, monitorutil.Owner{
+			Kind:      UptimeRobotMonitorKind,
+			Namespace: uptimeRobotMonitor.Namespace,
+			Name:      uptimeRobotMonitor.Name,
+		})
+	}
+	finalizer := monitorutil.GetUptimeRobotFinalizer()
+	if !uptimeRobotMonitor.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(uptimeRobotMonitor, finalizer) {
+			return ctrl.Result{}, nil
+		}
+		apiKey, err := r.APIKeyResolver.Resolve(ctx, uptimeRobotMonitor)
+		if err != nil {
+			// the referenced Secret is commonly deleted first along with its namespace, the monitor is left on
+			// UptimeRobot rather than blocking the deletion.
+			log.Log.Error(err, fmt.Sprintf("Api key of monitor %s not successfully resolved, the monitor is left on UptimeRobot", uptimeRobotMonitor.Spec.FriendlyName))
+			r.Recorder.Event(uptimeRobotMonitor, corev1.EventTypeWarning, MonitorDeleteFailedReason, fmt.Sprintf("Api key not successfully resolved, the monitor is left on UptimeRobot: %s", err))
+			controllerutil.RemoveFinalizer(uptimeRobotMonitor, finalizer)
+			return ctrl.Result{}, r.Update(ctx, uptimeRobotMonitor)
+		}
+		// the monitor is deleted by its recorded id as the spec may no longer name it.
+		if monitorId := uptimeRobotMonitor.Status.MonitorID; len(monitorId) > 0 {
+			err = r.UtilProvider.DeleteMonitorById(ctx, apiKey, monitorId)
+		} else {
+			err = r.UtilProvider.DeleteMonitor(ctx, apiKey, uptimeRobotMonitor.Spec.URL, annotations)
+		}
+		if err != nil && !monitorutil.IsMonitorNotFound(err) {
+			log.Log.Error(err, fmt.Sprintf("Monitor %s not successfully deleted", uptimeRobotMonitor.Spec.FriendlyName))
+			return ctrl.Result{}, err
+		}
+		log.Log.Info(fmt.Sprintf("Monitor %s successfully deleted", uptimeRobotMonitor.Spec.FriendlyName))
+		controllerutil.RemoveFinalizer(uptimeRobotMonitor, finalizer)
+		return ctrl.Result{}, r.Update(ctx, uptimeRobotMonitor)
+	}
+
+	if err := validateParameters(uptimeRobotMonitor.Spec.Parameters); err != nil {
+		log.Log.Error(err, fmt.Sprintf("Monitor %s parameters not valid", uptimeRobotMonitor.Spec.FriendlyName))
+		r.Recorder.Event(uptimeRobotMonitor, corev1.EventTypeWarning, MonitorSyncFailedReason, fmt.Sprintf("Monitor parameters not valid: %s", err))
+		meta.SetStatusCondition(&uptimeRobotMonitor.Status.Conditions, metav1.Condition{
+			Type:               monitoringv1alpha1.ConditionReady,
+			Status:             metav1.ConditionFalse,
+			Reason:             MonitorSyncFailedReason,
+			Message:            err.Error(),
+			ObservedGeneration: uptimeRobotMonitor.Generation,
+		})
+		// the spec needs to be fixed, which triggers a new reconcile.
+		return ctrl.Result{}, r.Status().Update(ctx, uptimeRobotMonitor)
+	}
+
+	apiKey, err := r.APIKeyResolver.Resolve(ctx, uptimeRobotMonitor)
+	if err != nil {
+		log.Log.Error(err, fmt.Sprintf("Api key of monitor %s not successfully resolved", uptimeRobotMonitor.Spec.FriendlyName))
+		r.Recorder.Event(uptimeRobotMonitor, corev1.EventTypeWarning, MonitorSyncFailedReason, fmt.Sprintf("Api key not successfully resolved: %s", err))
+		return ctrl.Result{}, err
+	}
+
+	if !controllerutil.ContainsFinalizer(uptimeRobotMonitor, finalizer) {
+		controllerutil.AddFinalizer(uptimeRobotMonitor, finalizer)
+		if err := r.Update(ctx, uptimeRobotMonitor); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if uptimeRobotMonitor.Status.ObservedGeneration == uptimeRobotMonitor.Generation &&
+		meta.IsStatusConditionTrue(uptimeRobotMonitor.Status.Conditions, monitoringv1alpha1.ConditionReady) {
+		drift, err := r.UtilProvider.GetMonitorDrift(ctx, apiKey, uptimeRobotMonitor.Spec.URL, annotations)
+		if err == nil && !drift.HasDrift() {
+			return ctrl.Result{RequeueAfter: r.ResyncPeriod}, nil
+		}
+		if err != nil {
+			log.Log.Error(err, fmt.Sprintf("Monitor %s drift not successfully checked", uptimeRobotMonitor.Spec.FriendlyName))
+		} else {
+			log.Log.Info(fmt.Sprintf("Monitor %s drifted: %s", uptimeRobotMonitor.Spec.FriendlyName, drift))
+			r.Recorder.Event(uptimeRobotMonitor, corev1.EventTypeWarning, MonitorDriftedReason, fmt.Sprintf("Monitor %s drifted: %s", uptimeRobotMonitor.Spec.FriendlyName, drift))
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               monitoringv1alpha1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             MonitorSyncedReason,
+		Message:            "Monitor successfully created/updated",
+		ObservedGeneration: uptimeRobotMonitor.Generation,
+	}
+	var monitorId string
+	if len(uptimeRobotMonitor.Status.MonitorID) > 0 {
+		// the monitor is edited in place so that renaming it keeps its history.
+		monitorId, err = r.UtilProvider.UpdateMonitor(ctx, apiKey, uptimeRobotMonitor.Status.MonitorID, uptimeRobotMonitor.Spec.URL, annotations)
+	} else {
+		monitorId, err = r.UtilProvider.CreateMonitor(ctx, apiKey, uptimeRobotMonitor.Spec.URL, annotations)
+	}
+	if err != nil {
+		log.Log.Error(err, fmt.Sprintf("Monitor %s not successfully created/updated", uptimeRobotMonitor.Spec.FriendlyName))
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = MonitorSyncFailedReason
+		condition.Message = err.Error()
+	} else {
+		log.Log.Info(fmt.Sprintf("Monitor %s successfully created/updated", uptimeRobotMonitor.Spec.FriendlyName))
+		now := metav1.Now()
+		uptimeRobotMonitor.Status.MonitorID = monitorId
+		uptimeRobotMonitor.Status.LastSyncedTime = &now
+		uptimeRobotMonitor.Status.ObservedGeneration = uptimeRobotMonitor.Generation
+	}
+	meta.SetStatusCondition(&uptimeRobotMonitor.Status.Conditions, condition)
+
+	if err := r.Status().Update(ctx, uptimeRobotMonitor); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err != nil {
+		return ctrl.Result{}, retryableSyncError(err)
+	}
+	return ctrl.Result{RequeueAfter: r.ResyncPeriod}, nil
+}
+
+// operatorParameters are set by the operator out of the typed fields and the recorded monitor, they can not be
+// set as parameters.
+var operatorParameters = []string{httputil.FriendlyNameField, httputil.UrlField, httputil.IdField}
+
+// validateParameters rejects the parameters that are set by the operator.
+func validateParameters(parameters map[string]string) error {
+	for _, parameter := range operatorParameters {
+		if _, exists := parameters[parameter]; exists {
+			return fmt.Errorf("parameter %s can not be set, it is set by the operator", parameter)
+		}
+	}
+	return nil
+}
+
+// buildAnnotationsFromSpec converts the spec to the annotations understood by monitorutil so that
+// UptimeRobotMonitor resources and ingresses share the same code path.
+func buildAnnotationsFromSpec(spec *monitoringv1alpha1.UptimeRobotMonitorSpec) map[string]string {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	annotations := map[string]string{}
+
+	for key, value := range spec.Parameters {
+		annotations[prefix+key] = value
+	}
+
+	annotations[prefix+httputil.FriendlyNameField] = spec.FriendlyName
+	annotations[prefix+httputil.TypeField] = string(spec.Type)
+	annotations[prefix+httputil.UrlField] = spec.URL
+
+	if len(spec.SubType) > 0 {
+		annotations[prefix+httputil.SubTypeField] = spec.SubType
+	}
+	if spec.Port != nil {
+		annotations[prefix+httputil.PortField] = strconv.Itoa(int(*spec.Port))
+	}
+	if spec.Keyword != nil {
+		annotations[prefix+httputil.KeywordTypeField] = spec.Keyword.Type
+		annotations[prefix+httputil.KeywordValueField] = spec.Keyword.Value
+		if len(spec.Keyword.CaseType) > 0 {
+			annotations[prefix+httputil.KeywordCaseTypeField] = spec.Keyword.CaseType
+		}
+	}
+	if spec.Interval != nil {
+		annotations[prefix+monitorutil.Interval] = strconv.Itoa(int(*spec.Interval))
+	}
+	if spec.Timeout != nil {
+		annotations[prefix+monitorutil.Timeout] = strconv.Itoa(int(*spec.Timeout))
+	}
+	if len(spec.AlertContacts) > 0 {
+		delimiter, found := os.LookupEnv(monitor.MonitorAlertContactsDelimiterEnv)
+		if !found {
+			delimiter = monitor.AlertContactsDelimiter
+		}
+		annotations[prefix+httputil.AlertContactsField] = strings.Join(spec.AlertContacts, delimiter)
+	}
+
+	return annotations
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *UptimeRobotMonitorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&monitoringv1alpha1.UptimeRobotMonitor{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
@@ -0,0 +1,264 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	monitoringv1alpha1 "github.com/bennsimon/uptimerobot-operator/api/v1alpha1"
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/service/monitor"
+	"github.com/stretchr/testify/mock"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func newUptimeRobotMonitor() *monitoringv1alpha1.UptimeRobotMonitor {
+	return &monitoringv1alpha1.UptimeRobotMonitor{
+		ObjectMeta: ctrl.ObjectMeta{Name: "tester", Namespace: "default"},
+		Spec: monitoringv1alpha1.UptimeRobotMonitorSpec{
+			FriendlyName: "tester",
+			Type:         monitoringv1alpha1.HTTPMonitor,
+			URL:          "https://test.localhost",
+		},
+	}
+}
+
+func TestUptimeRobotMonitorReconciler_Reconcile(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := monitoringv1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	tn := types.NamespacedName{Namespace: "default", Name: "tester"}
+	deleted := newUptimeRobotMonitor()
+	deleted.Finalizers = []string{monitorutil.GetUptimeRobotFinalizer()}
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+	synced := newUptimeRobotMonitor()
+	synced.Finalizers = []string{monitorutil.GetUptimeRobotFinalizer()}
+	synced.Status.MonitorID = "1"
+	synced.Status.Conditions = []metav1.Condition{{Type: monitoringv1alpha1.ConditionReady, Status: metav1.ConditionTrue, Reason: MonitorSyncedReason, LastTransitionTime: now}}
+	deletedSynced := deleted.DeepCopy()
+	deletedSynced.Status.MonitorID = "1"
+	operatorParameter := newUptimeRobotMonitor()
+	operatorParameter.Spec.Parameters = map[string]string{"id": "2"}
+
+	var testutilprovider *testUtilProvider
+	tests := []struct {
+		name            string
+		object          *monitoringv1alpha1.UptimeRobotMonitor
+		wantError       bool
+		wantReady       metav1.ConditionStatus
+		wantFinalizer   bool
+		wantResult      ctrl.Result
+		setupMocks      func()
+		verifyMocks     func()
+		skipStatusCheck bool
+	}{
+		{name: "should return nil when resource is not found", object: nil, setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+		}, verifyMocks: func() {
+			testutilprovider.AssertNotCalled(t, "CreateMonitor", mock.Anything, mock.Anything, mock.Anything)
+		}, skipStatusCheck: true},
+		{name: "should add finalizer and set ready condition when create monitor is successful", object: newUptimeRobotMonitor(), setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("CreateMonitor", "", "https://test.localhost", mock.IsType(map[string]string{})).Return("1", nil)
+		}, verifyMocks: func() {
+			testutilprovider.AssertExpectations(t)
+		}, wantReady: metav1.ConditionTrue, wantFinalizer: true, wantResult: ctrl.Result{RequeueAfter: time.Hour}},
+		{name: "should set ready condition to false without syncing when parameters set by the operator are set", object: operatorParameter, setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+		}, verifyMocks: func() {
+			testutilprovider.AssertNotCalled(t, "CreateMonitor", mock.Anything, mock.Anything, mock.Anything)
+		}, wantReady: metav1.ConditionFalse, wantFinalizer: false},
+		{name: "should set ready condition to false when create monitor fails", object: newUptimeRobotMonitor(), setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("CreateMonitor", "", "https://test.localhost", mock.IsType(map[string]string{})).Return("", errors.New("some error"))
+		}, verifyMocks: func() {
+			testutilprovider.AssertExpectations(t)
+		}, wantReady: metav1.ConditionFalse, wantFinalizer: true},
+		{name: "should set ready condition to false and return err to requeue when create monitor fails with transient error", object: newUptimeRobotMonitor(), setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("CreateMonitor", "", "https://test.localhost", mock.IsType(map[string]string{})).Return("", &monitorutil.APIError{StatusCode: http.StatusBadGateway})
+		}, verifyMocks: func() {
+			testutilprovider.AssertExpectations(t)
+		}, wantError: true, wantReady: metav1.ConditionFalse, wantFinalizer: true},
+		{name: "should requeue synced monitor without applying it when it did not drift", object: synced.DeepCopy(), setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("GetMonitorDrift", "", "https://test.localhost", mock.IsType(map[string]string{})).Return(&monitorutil.Drift{MonitorId: "1", Fields: []string{}}, nil)
+		}, verifyMocks: func() {
+			testutilprovider.AssertExpectations(t)
+			testutilprovider.AssertNotCalled(t, "CreateMonitor", mock.Anything, mock.Anything, mock.Anything)
+		}, wantReady: metav1.ConditionTrue, wantFinalizer: true, wantResult: ctrl.Result{RequeueAfter: time.Hour}},
+		{name: "should apply synced monitor again when it drifted", object: synced.DeepCopy(), setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("GetMonitorDrift", "", "https://test.localhost", mock.IsType(map[string]string{})).Return(&monitorutil.Drift{MonitorId: "1", Fields: []string{"url"}}, nil)
+			testutilprovider.On("UpdateMonitor", "", "1", "https://test.localhost", mock.IsType(map[string]string{})).Return("1", nil)
+		}, verifyMocks: func() {
+			testutilprovider.AssertExpectations(t)
+		}, wantReady: metav1.ConditionTrue, wantFinalizer: true, wantResult: ctrl.Result{RequeueAfter: time.Hour}},
+		{name: "should return err and keep finalizer when delete monitor fails", object: deleted.DeepCopy(), setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("DeleteMonitor", "", "https://test.localhost", mock.IsType(map[string]string{})).Return(errors.New("some error"))
+		}, verifyMocks: func() {
+			testutilprovider.AssertExpectations(t)
+		}, wantError: true, wantFinalizer: true, skipStatusCheck: true},
+		{name: "should remove finalizer when monitor no longer exists", object: deleted.DeepCopy(), setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("DeleteMonitor", "", "https://test.localhost", mock.IsType(map[string]string{})).Return(errors.New(monitor.MsgMonitorDoesNotExist))
+		}, verifyMocks: func() {
+			testutilprovider.AssertExpectations(t)
+		}, wantFinalizer: false, skipStatusCheck: true},
+		{name: "should delete monitor by its recorded id", object: deletedSynced.DeepCopy(), setupMocks: func() {
+			testutilprovider = &testUtilProvider{}
+			testutilprovider.On("DeleteMonitorById", "", "1").Return(nil)
+		}, verifyMocks: func() {
+			testutilprovider.AssertExpectations(t)
+			testutilprovider.AssertNotCalled(t, "DeleteMonitor", mock.Anything, mock.Anything, mock.Anything)
+		}, wantFinalizer: false, skipStatusCheck: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+			defer tt.verifyMocks()
+
+			builder := fake.NewClientBuilder().WithScheme(s)
+			if tt.object != nil {
+				builder = builder.WithObjects(tt.object)
+			}
+			r := &UptimeRobotMonitorReconciler{Client: builder.Build(), Scheme: s, Recorder: record.NewFakeRecorder(100), ResyncPeriod: time.Hour, UtilProvider: testutilprovider}
+
+			result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: tn})
+			if err == nil && tt.wantError {
+				t.Errorf("want %v got %v", nil, err)
+			}
+			if err != nil && !tt.wantError {
+				t.Errorf("want %v got %v", err, nil)
+			}
+			if result != tt.wantResult {
+				t.Errorf("result = %v, want %v", result, tt.wantResult)
+			}
+			if tt.object == nil {
+				return
+			}
+
+			got := &monitoringv1alpha1.UptimeRobotMonitor{}
+			if err := r.Get(context.Background(), tn, got); err != nil {
+				// the resource is gone once the last finalizer of a deleted resource is removed
+				if apierrors.IsNotFound(err) && !tt.wantFinalizer {
+					return
+				}
+				t.Fatal(err)
+			}
+			if controllerutil.ContainsFinalizer(got, monitorutil.GetUptimeRobotFinalizer()) != tt.wantFinalizer {
+				t.Errorf("finalizer present = %v, want %v", !tt.wantFinalizer, tt.wantFinalizer)
+			}
+			if !tt.skipStatusCheck && !meta.IsStatusConditionPresentAndEqual(got.Status.Conditions, monitoringv1alpha1.ConditionReady, tt.wantReady) {
+				t.Errorf("ready condition = %v, want %v", got.Status.Conditions, tt.wantReady)
+			}
+		})
+	}
+}
+
+func TestUptimeRobotMonitorReconciler_Reconcile_deletedApiKeySecret(t *testing.T) {
+	tn := types.NamespacedName{Namespace: "default", Name: "tester"}
+	now := metav1.Now()
+	deleted := newUptimeRobotMonitor()
+	deleted.Annotations = map[string]string{monitorutil.GetUptimeRobotMonitorPrefix() + monitorutil.ApiKeySecretAnnotation: "uptimerobot/api-key"}
+	deleted.Finalizers = []string{monitorutil.GetUptimeRobotFinalizer()}
+	deleted.DeletionTimestamp = &now
+	deleted.Status.MonitorID = "1"
+	c := fake.NewClientBuilder().WithScheme(newTemplateScheme(t)).WithObjects(deleted).Build()
+
+	testutilprovider := &testUtilProvider{}
+	recorder := record.NewFakeRecorder(10)
+	r := &UptimeRobotMonitorReconciler{Client: c, Recorder: recorder, ResyncPeriod: time.Hour, UtilProvider: testutilprovider,
+		APIKeyResolver: &APIKeyResolver{Client: c, SecretReader: c}}
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: tn}); err != nil {
+		t.Fatal(err)
+	}
+	testutilprovider.AssertNotCalled(t, "DeleteMonitorById", mock.Anything, mock.Anything)
+	got := &monitoringv1alpha1.UptimeRobotMonitor{}
+	if err := r.Get(context.Background(), tn, got); !apierrors.IsNotFound(err) {
+		t.Errorf("monitor = %v, %v, want it deleted once its finalizer is removed", got, err)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, MonitorDeleteFailedReason) {
+		t.Errorf("event = %v, want %v", event, MonitorDeleteFailedReason)
+	}
+}
+
+func Test_buildAnnotationsFromSpec(t *testing.T) {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	port := int32(8080)
+	interval := int32(300)
+	tests := []struct {
+		name string
+		spec monitoringv1alpha1.UptimeRobotMonitorSpec
+		want map[string]string
+	}{
+		{name: "should convert required fields", spec: monitoringv1alpha1.UptimeRobotMonitorSpec{
+			FriendlyName: "tester", Type: monitoringv1alpha1.HTTPMonitor, URL: "https://test.localhost",
+		}, want: map[string]string{
+			prefix + "friendly_name": "tester",
+			prefix + "type":          "HTTP",
+			prefix + "url":           "https://test.localhost",
+		}},
+		{name: "should convert typed fields and let them take precedence over parameters", spec: monitoringv1alpha1.UptimeRobotMonitorSpec{
+			FriendlyName:  "tester",
+			Type:          monitoringv1alpha1.PortMonitor,
+			URL:           "test.localhost",
+			SubType:       "HTTPS",
+			Port:          &port,
+			Interval:      &interval,
+			AlertContacts: []string{"1", "2_0_0"},
+			Keyword:       &monitoringv1alpha1.KeywordSpec{Type: "exists", Value: "ok"},
+			Parameters:    map[string]string{"interval": "60", "ignore_ssl_errors": "1"},
+		}, want: map[string]string{
+			prefix + "friendly_name":     "tester",
+			prefix + "type":              "Port",
+			prefix + "url":               "test.localhost",
+			prefix + "sub_type":          "HTTPS",
+			prefix + "port":              "8080",
+			prefix + "interval":          "300",
+			prefix + "alert_contacts":    "1-2_0_0",
+			prefix + "keyword_type":      "exists",
+			prefix + "keyword_value":     "ok",
+			prefix + "ignore_ssl_errors": "1",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildAnnotationsFromSpec(&tt.spec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildAnnotationsFromSpec() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+)
+
+// UtilProvider pushes monitors to UptimeRobot, it is shared by all reconcilers. apiKey selects the UptimeRobot
+// account, the UPTIME_ROBOT_API_KEY env var is used when empty.
+type UtilProvider interface {
+	// CreateMonitor creates or updates the monitor and returns its id.
+	CreateMonitor(ctx context.Context, apiKey string, host string, annotations map[string]string) (string, error)
+	// UpdateMonitor updates the monitor with the id in place and returns its id, it falls back to CreateMonitor when
+	// no monitor has the id.
+	UpdateMonitor(ctx context.Context, apiKey string, id string, host string, annotations map[string]string) (string, error)
+	DeleteMonitor(ctx context.Context, apiKey string, host string, annotations map[string]string) error
+	// GetMonitorDrift compares the monitor on UptimeRobot to the annotations.
+	GetMonitorDrift(ctx context.Context, apiKey string, host string, annotations map[string]string) (*monitorutil.Drift, error)
+	// ListMonitors lists the monitors whose friendly name or url contains search.
+	ListMonitors(ctx context.Context, apiKey string, search string) ([]monitorutil.Monitor, error)
+	DeleteMonitorById(ctx context.Context, apiKey string, id string) error
+	// SetMonitorPausedById pauses the monitor with the id, or resumes it when paused is false.
+	SetMonitorPausedById(ctx context.Context, apiKey string, id string, paused bool) error
+	// FindMonitorToAdopt finds the existing monitor to adopt by its id when not empty, or else its url or friendly
+	// name, and compares it to the annotations.
+	FindMonitorToAdopt(ctx context.Context, apiKey string, id string, host string, annotations map[string]string) (*monitorutil.Drift, error)
+	// GetMonitorStatuses fetches the live status of the monitors with the ids.
+	GetMonitorStatuses(ctx context.Context, apiKey string, ids []string) ([]monitorutil.MonitorStatus, error)
+}
+
+// MonitorUtilProvider is the UtilProvider backed by monitorutil.
+type MonitorUtilProvider struct{}
+
+var _ UtilProvider = &MonitorUtilProvider{}
+
+func (p *MonitorUtilProvider) CreateMonitor(ctx context.Context, apiKey string, host string, annotations map[string]string) (string, error) {
+	return monitorutil.CreateMonitor(ctx, apiKey, host, annotations)
+}
+
+func (p *MonitorUtilProvider) UpdateMonitor(ctx context.Context, apiKey string, id string, host string, annotations map[string]string) (string, error) {
+	return monitorutil.UpdateMonitor(ctx, apiKey, id, host, annotations)
+}
+
+func (p *MonitorUtilProvider) DeleteMonitor(ctx context.Context, apiKey string, host string, annotations map[string]string) error {
+	return monitorutil.DeleteMonitor(ctx, apiKey, host, annotations)
+}
+
+func (p *MonitorUtilProvider) GetMonitorDrift(ctx context.Context, apiKey string, host string, annotations map[string]string) (*monitorutil.Drift, error) {
+	return monitorutil.GetMonitorDrift(ctx, apiKey, host, annotations)
+}
+
+func (p *MonitorUtilProvider) ListMonitors(ctx context.Context, apiKey string, search string) ([]monitorutil.Monitor, error) {
+	return monitorutil.ListMonitors(ctx, apiKey, search)
+}
+
+func (p *MonitorUtilProvider) DeleteMonitorById(ctx context.Context, apiKey string, id string) error {
+	return monitorutil.DeleteMonitorById(ctx, apiKey, id)
+}
+
+func (p *MonitorUtilProvider) SetMonitorPausedById(ctx context.Context, apiKey string, id string, paused bool) error {
+	return monitorutil.SetMonitorPausedById(ctx, apiKey, id, paused)
+}
+
+func (p *MonitorUtilProvider) FindMonitorToAdopt(ctx context.Context, apiKey string, id string, host string, annotations map[string]string) (*monitorutil.Drift, error) {
+	return monitorutil.FindMonitorToAdopt(ctx, apiKey, id, host, annotations)
+}
+
+func (p *MonitorUtilProvider) GetMonitorStatuses(ctx context.Context, apiKey string, ids []string) ([]monitorutil.MonitorStatus, error) {
+	return monitorutil.GetMonitorStatuses(ctx, apiKey, ids)
+}
@@ -19,19 +19,30 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	gatewayv1beta1 "sigs.k8s.io/gateway-api/apis/v1beta1"
 
+	monitoringv1alpha1 "github.com/bennsimon/uptimerobot-operator/api/v1alpha1"
 	"github.com/bennsimon/uptimerobot-operator/controllers"
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	"github.com/bennsimon/uptimerobot-operator/webhooks"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -43,6 +54,8 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
+	utilruntime.Must(monitoringv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(gatewayv1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -50,11 +63,85 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var resyncPeriod time.Duration
+	var friendlyNameTemplate string
+	var clusterId string
+	var gcInterval time.Duration
+	var gcDryRun bool
+	var enableGatewayApi bool
+	var enableWebhooks bool
+	var webhookWarnOnly bool
+	var minInterval int
+	var watchNamespaces string
+	var ingressLabelSelector string
+	var namespaceLabelSelector string
+	var ingressClasses string
+	var excludedIngressClasses string
+	var autoEnabledIngressClasses string
+	var statusExportInterval time.Duration
+	var apiRateLimit int
+	var apiRateBurst int
+	var onDisable string
+	var dryRun bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&resyncPeriod, "resync-period", time.Hour,
+		"The period after which synced monitors are checked for drift against UptimeRobot and applied again if they drifted. "+
+			"Zero disables it.")
+	flag.StringVar(&friendlyNameTemplate, "friendly-name-template", monitorutil.DefaultFriendlyNameTemplate,
+		"The template of the friendly name of each host's monitor of ingresses with more than one host or path. "+
+			"{{.FriendlyName}}, {{.Host}}, {{.Path}}, {{.Name}} and {{.Namespace}} are available.")
+	flag.StringVar(&clusterId, "cluster-id", "",
+		"Identifies the cluster in the friendly names of the monitors, e.g. tester [<cluster-id>/Ingress/default/tester]. "+
+			"Monitors are not marked when empty.")
+	flag.DurationVar(&gcInterval, "gc-interval", 0,
+		"The interval at which monitors marked with --cluster-id whose resource no longer exists are deleted. "+
+			"Zero disables the garbage collection.")
+	flag.BoolVar(&gcDryRun, "gc-dry-run", false,
+		"Only log the orphaned monitors found by the garbage collection instead of deleting them.")
+	flag.BoolVar(&enableGatewayApi, "enable-gateway-api", false,
+		"Create monitors for Gateway API HTTPRoutes, the Gateway API CRDs need to be installed.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Validate the uptimerobot-monitor annotations of ingresses on admission, "+
+			"the webhook server needs a certificate in /tmp/k8s-webhook-server/serving-certs.")
+	flag.BoolVar(&webhookWarnOnly, "webhook-warn-only", false,
+		"Admit ingresses with invalid uptimerobot-monitor annotations and return the problems as warnings.")
+	flag.IntVar(&minInterval, "min-interval", monitorutil.DefaultMinInterval,
+		"The shortest monitoring interval in seconds allowed by the plan of the UptimeRobot account.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"The comma separated namespaces whose resources are watched, the operator only needs Roles in them. "+
+			"Every namespace is watched when empty.")
+	flag.StringVar(&ingressLabelSelector, "ingress-label-selector", "",
+		"Only manage the monitors of the ingresses matching this label selector e.g. uptimerobot=enabled. "+
+			"Every ingress is managed when empty.")
+	flag.StringVar(&namespaceLabelSelector, "namespace-label-selector", "",
+		"Only manage the monitors of the ingresses, services and HTTPRoutes of the namespaces matching this label selector. "+
+			"Every namespace is managed when empty.")
+	flag.StringVar(&ingressClasses, "ingress-class", "",
+		"The comma separated ingress classes whose ingresses are managed, by spec.ingressClassName or else the "+
+			"kubernetes.io/ingress.class annotation. Ingresses of every class are managed when empty.")
+	flag.StringVar(&excludedIngressClasses, "exclude-ingress-class", "",
+		"The comma separated ingress classes whose ingresses are never managed, e.g. of an internal ingress controller.")
+	flag.StringVar(&autoEnabledIngressClasses, "auto-enable-ingress-class", "",
+		"The comma separated ingress classes whose ingresses are monitored without the uptimerobot-monitor annotation, "+
+			"unless it is set to false.")
+	flag.DurationVar(&statusExportInterval, "status-export-interval", 0,
+		"The interval at which the live status of the monitors of ingresses is fetched from UptimeRobot and exported "+
+			"as uptimerobot_monitor_* metrics. Zero disables the export.")
+	flag.IntVar(&apiRateLimit, "api-rate-limit", 0,
+		"The requests per minute sent to the UptimeRobot api, further requests wait for their turn. "+
+			"Zero disables the limit, e.g. set it to 10 on the free plan of UptimeRobot.")
+	flag.IntVar(&apiRateBurst, "api-rate-burst", 1,
+		"The requests sent to the UptimeRobot api at once before --api-rate-limit applies.")
+	flag.StringVar(&onDisable, "on-disable", monitorutil.OnDisableDelete,
+		"What happens to the monitors of a resource once the uptimerobot-monitor annotation is set to false or removed, "+
+			"delete or pause them. The uptimerobot-monitor-on-disable annotation overrides it per resource.")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"Only log the monitors that would be created, updated, deleted, paused or resumed instead of changing them "+
+			"on UptimeRobot, their number is exported as the uptimerobot_operator_dry_run_pending_changes metric.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -63,8 +150,35 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	monitorutil.SetRateLimit(apiRateLimit, apiRateBurst)
+
+	_ingressLabelSelector, err := parseLabelSelector(ingressLabelSelector)
+	if err != nil {
+		setupLog.Error(err, "unable to parse ingress label selector")
+		os.Exit(1)
+	}
+	_namespaceLabelSelector, err := parseLabelSelector(namespaceLabelSelector)
+	if err != nil {
+		setupLog.Error(err, "unable to parse namespace label selector")
+		os.Exit(1)
+	}
+	namespaces := splitList(watchNamespaces)
+	var newCache cache.NewCacheFunc
+	if len(namespaces) > 0 {
+		newCache = cache.MultiNamespacedCacheBuilder(namespaces)
+	}
+	var ingressClassFilter *controllers.IngressClassFilter
+	if len(ingressClasses) > 0 || len(excludedIngressClasses) > 0 || len(autoEnabledIngressClasses) > 0 {
+		ingressClassFilter = &controllers.IngressClassFilter{
+			Allowed:     splitList(ingressClasses),
+			Denied:      splitList(excludedIngressClasses),
+			AutoEnabled: splitList(autoEnabledIngressClasses),
+		}
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
+		NewCache:               newCache,
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
 		HealthProbeBindAddress: probeAddr,
@@ -87,15 +201,134 @@ func main() {
 		os.Exit(1)
 	}
 
+	if gcInterval > 0 && len(clusterId) == 0 {
+		setupLog.Error(nil, "--cluster-id is required by the garbage collection")
+		os.Exit(1)
+	}
+	if onDisable != monitorutil.OnDisableDelete && onDisable != monitorutil.OnDisablePause {
+		setupLog.Error(nil, "--on-disable must be delete or pause")
+		os.Exit(1)
+	}
+	_friendlyNameTemplate, err := monitorutil.ParseFriendlyNameTemplate(friendlyNameTemplate)
+	if err != nil {
+		setupLog.Error(err, "unable to parse friendly name template")
+		os.Exit(1)
+	}
+
+	var utilProvider controllers.UtilProvider = &controllers.MonitorUtilProvider{}
+	var recorder record.EventRecorder = mgr.GetEventRecorderFor("uptimerobot-operator")
+	reconcileClient := mgr.GetClient()
+	if dryRun {
+		dryRunUtilProvider := &controllers.DryRunUtilProvider{UtilProvider: utilProvider}
+		metrics.Registry.MustRegister(dryRunUtilProvider)
+		utilProvider = dryRunUtilProvider
+		recorder = &controllers.DryRunRecorder{EventRecorder: recorder}
+		// the finalizers and sync status of the resources are only validated by the api server, so that they are not
+		// marked as synced with monitors that do not exist.
+		reconcileClient = client.NewDryRunClient(reconcileClient)
+		setupLog.Info("running in dry-run mode, monitors are not changed on UptimeRobot")
+	}
+	apiKeyResolver := &controllers.APIKeyResolver{
+		Client:       mgr.GetClient(),
+		SecretReader: mgr.GetAPIReader(),
+	}
+	if err = (&controllers.APIKeySecretReconciler{
+		Resolver: apiKeyResolver,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "APIKeySecret")
+		os.Exit(1)
+	}
+	hostMonitorSyncer := controllers.HostMonitorSyncer{
+		Client:               reconcileClient,
+		Recorder:             recorder,
+		ResyncPeriod:         resyncPeriod,
+		FriendlyNameTemplate: _friendlyNameTemplate,
+		ClusterId:            clusterId,
+		APIKeyResolver:       apiKeyResolver,
+		TemplateResolver:     &controllers.TemplateResolver{Client: mgr.GetClient()},
+		NamespaceSelector:    _namespaceLabelSelector,
+		OnDisable:            onDisable,
+		UtilProvider:         utilProvider,
+	}
+	ingressMonitorSyncer := hostMonitorSyncer
+	ingressMonitorSyncer.LabelSelector = _ingressLabelSelector
+	ingressMonitorSyncer.IngressClassFilter = ingressClassFilter
 	_uptimeRobotReconciler := &controllers.UptimerobotReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		HostMonitorSyncer: ingressMonitorSyncer,
+		Scheme:            mgr.GetScheme(),
 	}
-	_uptimeRobotReconciler.UtilProvider = _uptimeRobotReconciler
 	if err = (_uptimeRobotReconciler).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Uptimerobot")
 		os.Exit(1)
 	}
+	if err = (&controllers.ServiceReconciler{
+		HostMonitorSyncer: hostMonitorSyncer,
+		Scheme:            mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Service")
+		os.Exit(1)
+	}
+	if enableGatewayApi {
+		if err = (&controllers.HTTPRouteReconciler{
+			HostMonitorSyncer: hostMonitorSyncer,
+			Scheme:            mgr.GetScheme(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "HTTPRoute")
+			os.Exit(1)
+		}
+	}
+	if err = (&controllers.UptimeRobotMonitorReconciler{
+		Client:         reconcileClient,
+		Scheme:         mgr.GetScheme(),
+		Recorder:       recorder,
+		ResyncPeriod:   resyncPeriod,
+		ClusterId:      clusterId,
+		APIKeyResolver: apiKeyResolver,
+		UtilProvider:   utilProvider,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "UptimeRobotMonitor")
+		os.Exit(1)
+	}
+	metricKinds := []string{controllers.IngressKind, controllers.ServiceKind, controllers.UptimeRobotMonitorKind}
+	if enableGatewayApi {
+		metricKinds = append(metricKinds, controllers.HTTPRouteKind)
+	}
+	metrics.Registry.MustRegister(&controllers.MonitorCollector{Client: mgr.GetClient(), Kinds: metricKinds})
+	if gcInterval > 0 {
+		if err = mgr.Add(&controllers.MonitorGarbageCollector{
+			Client:       mgr.GetClient(),
+			ClusterId:    clusterId,
+			Interval:     gcInterval,
+			DryRun:       gcDryRun,
+			Namespaces:   namespaces,
+			UtilProvider: utilProvider,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up garbage collection")
+			os.Exit(1)
+		}
+	}
+	if statusExportInterval > 0 {
+		statusExporter := &controllers.MonitorStatusExporter{
+			Client:         mgr.GetClient(),
+			Interval:       statusExportInterval,
+			APIKeyResolver: apiKeyResolver,
+			UtilProvider:   utilProvider,
+		}
+		if err = mgr.Add(statusExporter); err != nil {
+			setupLog.Error(err, "unable to set up monitor status export")
+			os.Exit(1)
+		}
+		metrics.Registry.MustRegister(statusExporter)
+	}
+	if enableWebhooks {
+		if err = (&webhooks.IngressValidator{
+			MinInterval: minInterval,
+			WarnOnly:    webhookWarnOnly,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Ingress")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -113,3 +346,19 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// parseLabelSelector parses the label selector of a flag, an empty selector selects everything and is nil.
+func parseLabelSelector(selector string) (labels.Selector, error) {
+	if len(selector) == 0 {
+		return nil, nil
+	}
+	return labels.Parse(selector)
+}
+
+// splitList splits the comma separated values of a flag, an empty flag has no values.
+func splitList(value string) []string {
+	if len(value) == 0 {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
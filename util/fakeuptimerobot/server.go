@@ -0,0 +1,337 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fakeuptimerobot provides a fake UptimeRobot v2 api keeping the monitors and alert contacts of a single
+// account in memory, so that the operator can be tested end to end without reaching UptimeRobot.
+package fakeuptimerobot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+// defaultLimit is the page size of the get endpoints when no limit is requested, it is also the maximum.
+const defaultLimit = 50
+
+// numericFields are the monitor fields returned as numbers by the api.
+var numericFields = map[string]bool{
+	httputil.IdField:              true,
+	httputil.TypeField:            true,
+	httputil.SubTypeField:         true,
+	httputil.PortField:            true,
+	httputil.KeywordTypeField:     true,
+	httputil.KeywordCaseTypeField: true,
+	httputil.HttpAuthTypeField:    true,
+	"interval":                    true,
+	"timeout":                     true,
+	"status":                      true,
+}
+
+// Monitor is a monitor of the fake account, Fields holds every parameter it was created or edited with.
+type Monitor struct {
+	Id     int
+	Fields map[string]string
+}
+
+// FriendlyName returns the friendly_name of the monitor.
+func (m Monitor) FriendlyName() string {
+	return m.Fields[httputil.FriendlyNameField]
+}
+
+// Url returns the url of the monitor.
+func (m Monitor) Url() string {
+	return m.Fields[httputil.UrlField]
+}
+
+// AlertContact is an alert contact of the fake account.
+type AlertContact struct {
+	Id           string
+	FriendlyName string
+}
+
+// Server is a fake UptimeRobot v2 api serving newMonitor, editMonitor, deleteMonitor, getMonitors and
+// getAlertContacts. Requests are only accepted with APIKey.
+type Server struct {
+	*httptest.Server
+	APIKey string
+
+	mu            sync.Mutex
+	nextId        int
+	monitors      map[int]map[string]string
+	alertContacts []AlertContact
+	// apiKeys counts the requests by the api key they were sent with, including the rejected ones.
+	apiKeys map[string]int
+}
+
+// NewServer starts a fake api accepting requests with apiKey, it has to be closed by the caller.
+func NewServer(apiKey string) *Server {
+	s := &Server{APIKey: apiKey, nextId: 777000000, monitors: map[int]map[string]string{}, apiKeys: map[string]int{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+httputil.NewMonitorEndpoint, s.handle(s.newMonitor))
+	mux.HandleFunc("/"+httputil.EditMonitorEndpoint, s.handle(s.editMonitor))
+	mux.HandleFunc("/"+httputil.DeleteMonitorEndpoint, s.handle(s.deleteMonitor))
+	mux.HandleFunc("/"+httputil.GetMonitorsEndpoint, s.handle(s.getMonitors))
+	mux.HandleFunc("/"+httputil.GetAlertContactsEndpoint, s.handle(s.getAlertContacts))
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// BaseURL returns the url to set UPTIME_ROBOT_API_URL to.
+func (s *Server) BaseURL() string {
+	return s.URL + "/"
+}
+
+// Monitors returns the monitors of the account ordered by id.
+func (s *Server) Monitors() []Monitor {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	monitors := make([]Monitor, 0, len(s.monitors))
+	for _, id := range s.sortedIds() {
+		fields := map[string]string{}
+		for key, value := range s.monitors[id] {
+			fields[key] = value
+		}
+		monitors = append(monitors, Monitor{Id: id, Fields: fields})
+	}
+	return monitors
+}
+
+// FindMonitor returns the monitor with the friendly name.
+func (s *Server) FindMonitor(friendlyName string) (Monitor, bool) {
+	for _, monitor := range s.Monitors() {
+		if monitor.FriendlyName() == friendlyName {
+			return monitor, true
+		}
+	}
+	return Monitor{}, false
+}
+
+// ReceivedAPIKeys returns the number of requests received by the api key they were sent with.
+func (s *Server) ReceivedAPIKeys() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	apiKeys := map[string]int{}
+	for apiKey, count := range s.apiKeys {
+		apiKeys[apiKey] = count
+	}
+	return apiKeys
+}
+
+// AddAlertContact adds an alert contact to the account and returns its id.
+func (s *Server) AddAlertContact(friendlyName string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextId++
+	id := strconv.Itoa(s.nextId)
+	s.alertContacts = append(s.alertContacts, AlertContact{Id: id, FriendlyName: friendlyName})
+	return id
+}
+
+// handle authenticates the form posted to an endpoint before passing it to handler, errors returned by the
+// handler are answered like the api does with a failed stat.
+func (s *Server) handle(handler func(form map[string]string) (map[string]interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		form := map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+
+		var result map[string]interface{}
+		var err error
+		s.mu.Lock()
+		s.apiKeys[form[httputil.ApiKeyField]]++
+		s.mu.Unlock()
+		if form[httputil.ApiKeyField] != s.APIKey {
+			err = fmt.Errorf("api_key is wrong")
+		} else {
+			delete(form, httputil.ApiKeyField)
+			delete(form, httputil.FormatKeyField)
+			s.mu.Lock()
+			result, err = handler(form)
+			s.mu.Unlock()
+		}
+		if err != nil {
+			result = map[string]interface{}{
+				httputil.StatField:  "fail",
+				httputil.ErrorField: map[string]interface{}{"type": "invalid_parameter", httputil.MessageField: err.Error()},
+			}
+		} else {
+			result[httputil.StatField] = "ok"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(result)
+	}
+}
+
+func (s *Server) newMonitor(form map[string]string) (map[string]interface{}, error) {
+	for _, field := range []string{httputil.FriendlyNameField, httputil.TypeField, httputil.UrlField} {
+		if len(form[field]) == 0 {
+			return nil, fmt.Errorf("%s parameter is missing", field)
+		}
+	}
+	s.nextId++
+	form["status"] = "1"
+	s.monitors[s.nextId] = form
+	return map[string]interface{}{"monitor": map[string]interface{}{httputil.IdField: s.nextId, "status": 1}}, nil
+}
+
+func (s *Server) editMonitor(form map[string]string) (map[string]interface{}, error) {
+	id, monitor, err := s.lookUpMonitor(form)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range form {
+		if key != httputil.IdField {
+			monitor[key] = value
+		}
+	}
+	return map[string]interface{}{"monitor": map[string]interface{}{httputil.IdField: id}}, nil
+}
+
+func (s *Server) deleteMonitor(form map[string]string) (map[string]interface{}, error) {
+	id, _, err := s.lookUpMonitor(form)
+	if err != nil {
+		return nil, err
+	}
+	delete(s.monitors, id)
+	return map[string]interface{}{"monitor": map[string]interface{}{httputil.IdField: id}}, nil
+}
+
+func (s *Server) lookUpMonitor(form map[string]string) (int, map[string]string, error) {
+	id, err := strconv.Atoi(form[httputil.IdField])
+	if err != nil {
+		return 0, nil, fmt.Errorf("id parameter is invalid")
+	}
+	monitor, exists := s.monitors[id]
+	if !exists {
+		return 0, nil, fmt.Errorf("monitor not found")
+	}
+	return id, monitor, nil
+}
+
+// getMonitors lists the monitors whose friendly name or url contains search, or whose id is in the dash separated
+// monitors parameter.
+func (s *Server) getMonitors(form map[string]string) (map[string]interface{}, error) {
+	var ids map[string]bool
+	if len(form[httputil.MonitorsField]) > 0 {
+		ids = map[string]bool{}
+		for _, id := range strings.Split(form[httputil.MonitorsField], "-") {
+			ids[id] = true
+		}
+	}
+	search := form[httputil.SearchField]
+
+	matches := make([]interface{}, 0)
+	for _, id := range s.sortedIds() {
+		monitor := s.monitors[id]
+		if ids != nil && !ids[strconv.Itoa(id)] {
+			continue
+		}
+		if len(search) > 0 && !strings.Contains(monitor[httputil.FriendlyNameField], search) && !strings.Contains(monitor[httputil.UrlField], search) {
+			continue
+		}
+		matches = append(matches, toResult(id, monitor))
+	}
+	offset, limit := pagination(form)
+	return map[string]interface{}{
+		"pagination":           map[string]interface{}{httputil.OffsetField: offset, httputil.LimitField: limit, httputil.TotalField: len(matches)},
+		httputil.MonitorsField: page(matches, offset, limit),
+	}, nil
+}
+
+func (s *Server) getAlertContacts(form map[string]string) (map[string]interface{}, error) {
+	alertContacts := make([]interface{}, 0, len(s.alertContacts))
+	for _, alertContact := range s.alertContacts {
+		alertContacts = append(alertContacts, map[string]interface{}{
+			httputil.IdField:           alertContact.Id,
+			httputil.FriendlyNameField: alertContact.FriendlyName,
+			httputil.TypeField:         2,
+			"status":                   2,
+		})
+	}
+	offset, limit := pagination(form)
+	return map[string]interface{}{
+		httputil.OffsetField:        offset,
+		httputil.LimitField:         limit,
+		httputil.TotalField:         len(alertContacts),
+		httputil.AlertContactsField: page(alertContacts, offset, limit),
+	}, nil
+}
+
+func (s *Server) sortedIds() []int {
+	ids := make([]int, 0, len(s.monitors))
+	for id := range s.monitors {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// toResult returns the monitor as returned by getMonitors, alert contacts are only returned by the api when
+// requested and are left out.
+func toResult(id int, monitor map[string]string) map[string]interface{} {
+	result := map[string]interface{}{httputil.IdField: id}
+	for key, value := range monitor {
+		if key == httputil.AlertContactsField {
+			continue
+		}
+		if number, err := strconv.Atoi(value); err == nil && numericFields[key] {
+			result[key] = number
+		} else {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+func pagination(form map[string]string) (int, int) {
+	offset, err := strconv.Atoi(form[httputil.OffsetField])
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	limit, err := strconv.Atoi(form[httputil.LimitField])
+	if err != nil || limit <= 0 || limit > defaultLimit {
+		limit = defaultLimit
+	}
+	return offset, limit
+}
+
+func page(items []interface{}, offset int, limit int) []interface{} {
+	if offset >= len(items) {
+		return []interface{}{}
+	}
+	end := offset + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
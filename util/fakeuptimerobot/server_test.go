@@ -0,0 +1,103 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fakeuptimerobot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+func post(t *testing.T, server *Server, endpoint string, form url.Values) map[string]interface{} {
+	res, err := http.PostForm(server.BaseURL()+endpoint, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var result map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestServer_authentication(t *testing.T) {
+	server := NewServer("key")
+	defer server.Close()
+
+	result := post(t, server, httputil.GetMonitorsEndpoint, url.Values{httputil.ApiKeyField: {"other"}})
+	if result[httputil.StatField] != "fail" {
+		t.Errorf("getMonitors() with wrong api key = %v", result)
+	}
+	result = post(t, server, httputil.GetMonitorsEndpoint, url.Values{httputil.ApiKeyField: {"key"}})
+	if result[httputil.StatField] != "ok" {
+		t.Errorf("getMonitors() = %v", result)
+	}
+}
+
+func TestServer_getMonitors(t *testing.T) {
+	server := NewServer("key")
+	defer server.Close()
+	for i := 0; i < 3; i++ {
+		result := post(t, server, httputil.NewMonitorEndpoint, url.Values{
+			httputil.ApiKeyField:       {"key"},
+			httputil.FriendlyNameField: {"example-" + strconv.Itoa(i)},
+			httputil.TypeField:         {"1"},
+			httputil.UrlField:          {"https://example.localhost"},
+		})
+		if result[httputil.StatField] != "ok" {
+			t.Fatalf("newMonitor() = %v", result)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		form      url.Values
+		wantCount int
+		wantTotal float64
+	}{
+		{name: "should search friendly names", form: url.Values{httputil.SearchField: {"example-1"}}, wantCount: 1, wantTotal: 1},
+		{name: "should search urls", form: url.Values{httputil.SearchField: {"example.localhost"}}, wantCount: 3, wantTotal: 3},
+		{name: "should paginate", form: url.Values{httputil.OffsetField: {"2"}, httputil.LimitField: {"2"}}, wantCount: 1, wantTotal: 3},
+		{name: "should filter by ids", form: url.Values{httputil.MonitorsField: {strconv.Itoa(server.Monitors()[0].Id)}}, wantCount: 1, wantTotal: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.form.Set(httputil.ApiKeyField, "key")
+			result := post(t, server, httputil.GetMonitorsEndpoint, tt.form)
+			monitors, _ := result[httputil.MonitorsField].([]interface{})
+			pagination, _ := result["pagination"].(map[string]interface{})
+			if len(monitors) != tt.wantCount || pagination[httputil.TotalField] != tt.wantTotal {
+				t.Errorf("getMonitors() = %v", result)
+			}
+		})
+	}
+}
+
+func TestServer_deleteMonitor(t *testing.T) {
+	server := NewServer("key")
+	defer server.Close()
+
+	result := post(t, server, httputil.DeleteMonitorEndpoint, url.Values{httputil.ApiKeyField: {"key"}, httputil.IdField: {"1"}})
+	if result[httputil.StatField] != "fail" {
+		t.Errorf("deleteMonitor() of missing monitor = %v", result)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/service"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+// FindMonitorToAdopt finds the existing monitor to adopt for the annotations, the monitor with the id when it is not
+// empty or else the monitor with the url of the annotations, or else their friendly name. The returned drift is what
+// adopting it changes, it is missing when no monitor matches. A monitor with the id must exist.
+func FindMonitorToAdopt(ctx context.Context, apiKey string, id string, host string, ingressAnnotations map[string]string) (*Drift, error) {
+	drift, err := apiCoalescer.do(ctx, coalesceKey("adopt "+id, apiKey, host, ingressAnnotations), func() (interface{}, error) {
+		return findMonitorToAdopt(id, host, ingressAnnotations, newMonitorService(ctx, apiKey))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return drift.(*Drift), nil
+}
+
+func findMonitorToAdopt(id string, host string, ingressAnnotations map[string]string, service service.IService) (*Drift, error) {
+	dataMap, err := BuildMonitorPayload("", host, ingressAnnotations)
+	if err != nil {
+		return nil, err
+	}
+
+	var remoteMonitor map[string]interface{}
+	if len(id) > 0 {
+		remoteMonitor, err = findMonitorById(id, service)
+		if err != nil {
+			return nil, err
+		}
+		if remoteMonitor == nil {
+			return nil, fmt.Errorf("monitor %s to adopt not found", id)
+		}
+		return compareMonitor(dataMap, remoteMonitor), nil
+	}
+
+	remoteMonitor, err = findMonitorByUrl(fmt.Sprint(dataMap[Url]), service)
+	if err != nil {
+		return nil, err
+	}
+	if remoteMonitor == nil {
+		remoteMonitor, err = findMonitor(fmt.Sprint(dataMap[httputil.FriendlyNameField]), service)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if remoteMonitor == nil {
+		return &Drift{Missing: true}, nil
+	}
+	return compareMonitor(dataMap, remoteMonitor), nil
+}
+
+// findMonitorByUrl fetches the first monitor with the exact url, it returns nil if none matches.
+func findMonitorByUrl(url string, service service.IService) (map[string]interface{}, error) {
+	resultMap, err := service.HttpInitiatePostRequest(httputil.GetMonitorsEndpoint, map[string]interface{}{
+		httputil.SearchField: url,
+	})
+	if err != nil {
+		return nil, err
+	}
+	monitors, _ := resultMap[httputil.MonitorsField].([]interface{})
+	for _, m := range monitors {
+		_monitor, ok := m.(map[string]interface{})
+		if ok && fmt.Sprint(_monitor[httputil.UrlField]) == url && _monitor[httputil.IdField] != nil {
+			return _monitor, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_findMonitorToAdopt(t *testing.T) {
+	prefix := GetUptimeRobotMonitorPrefix()
+	annotations := map[string]string{
+		prefix + httputil.FriendlyNameField: "example",
+		prefix + Interval:                   "300",
+	}
+	handMade := map[string]interface{}{
+		httputil.IdField:           float64(7),
+		httputil.FriendlyNameField: "Example website",
+		httputil.UrlField:          "https://example.localhost",
+		Interval:                   float64(60),
+	}
+	tests := []struct {
+		name    string
+		id      string
+		setup   func(m *MockMonitorService)
+		want    *Drift
+		wantErr bool
+	}{
+		{name: "should find monitor by id", id: "7", setup: func(m *MockMonitorService) {
+			m.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, map[string]interface{}{httputil.MonitorsField: "7"}).Return(map[string]interface{}{
+				httputil.MonitorsField: []interface{}{handMade},
+			}, nil)
+		}, want: &Drift{MonitorId: "7", Fields: []string{httputil.FriendlyNameField, Interval}}},
+		{name: "should return error if no monitor has the id", id: "8", setup: func(m *MockMonitorService) {
+			m.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, map[string]interface{}{httputil.MonitorsField: "8"}).Return(map[string]interface{}{
+				httputil.MonitorsField: []interface{}{},
+			}, nil)
+		}, wantErr: true},
+		{name: "should find monitor by url", setup: func(m *MockMonitorService) {
+			m.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, map[string]interface{}{httputil.SearchField: "https://example.localhost"}).Return(map[string]interface{}{
+				httputil.MonitorsField: []interface{}{handMade},
+			}, nil)
+		}, want: &Drift{MonitorId: "7", Fields: []string{httputil.FriendlyNameField, Interval}}},
+		{name: "should find monitor by friendly name", setup: func(m *MockMonitorService) {
+			m.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, map[string]interface{}{httputil.SearchField: "https://example.localhost"}).Return(map[string]interface{}{
+				httputil.MonitorsField: []interface{}{},
+			}, nil)
+			m.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, map[string]interface{}{httputil.SearchField: "example"}).Return(map[string]interface{}{
+				httputil.MonitorsField: []interface{}{map[string]interface{}{httputil.IdField: float64(9), httputil.FriendlyNameField: "example", httputil.UrlField: "http://example.localhost"}},
+			}, nil)
+		}, want: &Drift{MonitorId: "9", Fields: []string{httputil.UrlField}}},
+		{name: "should return missing drift if no monitor matches", setup: func(m *MockMonitorService) {
+			m.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, mock.Anything).Return(map[string]interface{}{
+				httputil.MonitorsField: []interface{}{},
+			}, nil)
+		}, want: &Drift{Missing: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testStruct := new(MockMonitorService)
+			tt.setup(testStruct)
+			got, err := findMonitorToAdopt(tt.id, "https://example.localhost", annotations, testStruct)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("findMonitorToAdopt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findMonitorToAdopt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
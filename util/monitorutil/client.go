@@ -0,0 +1,156 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/service"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/service/monitor"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+// APIError is returned when the UptimeRobot api answers with a status other than 200.
+type APIError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is the delay before the next request requested by a 429 response.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("uptimerobot api returned %d: %s", e.StatusCode, e.Body)
+}
+
+// IsTransientError reports whether the request that failed with err may succeed when retried, i.e. network
+// errors, rate limiting and server errors. Any other error needs the monitor configuration to be fixed.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode == http.StatusTooManyRequests || apiError.StatusCode >= http.StatusInternalServerError
+	}
+	var netError net.Error
+	return errors.As(err, &netError)
+}
+
+// apiClient sends the requests of the tooling to the UptimeRobot api. Unlike the tooling it keeps the status
+// code and the network errors of failed requests so that they can be classified by IsTransientError.
+type apiClient struct {
+	*monitor.MonitorService
+	httpClient *http.Client
+	// ctx of the caller, the tooling's requests take no context. The requests waiting for their turn or sent are
+	// canceled with it.
+	ctx context.Context
+	// apiKey of the account, the UPTIME_ROBOT_API_KEY env var is used when empty.
+	apiKey string
+}
+
+// newMonitorService returns a monitor service sending its requests to the account of apiKey until ctx is done. The
+// client is returned rather than the tooling's service, whose own HttpInitiatePostRequest would send the requests
+// with the env api key.
+func newMonitorService(ctx context.Context, apiKey string) service.IService {
+	monitorService := monitor.New()
+	client := &apiClient{MonitorService: monitorService, httpClient: http.DefaultClient, ctx: ctx, apiKey: apiKey}
+	monitorService.IService = client
+	return client
+}
+
+func (c *apiClient) HttpInitiatePostRequest(endpoint string, dataMap map[string]interface{}) (map[string]interface{}, error) {
+	for attempt := 0; ; attempt++ {
+		if err := apiRateLimiter.wait(c.ctx); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		resultMap, err := c.postRequest(endpoint, dataMap)
+		observeRequest(endpoint, start, err, resultMap != nil && resultMap[httputil.StatField] == "fail")
+		var apiError *APIError
+		if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusTooManyRequests || attempt == maxRateLimitedRetries {
+			return resultMap, err
+		}
+		apiRateLimiter.pause(apiError.RetryAfter)
+	}
+}
+
+func (c *apiClient) postRequest(endpoint string, dataMap map[string]interface{}) (map[string]interface{}, error) {
+	// same fields as dropped by the tooling before sending a request.
+	for _, field := range []string{httputil.FormatKeyField, httputil.ApiKeyField, httputil.HttpMethodField, httputil.PostValueField, httputil.PostContentTypeField} {
+		delete(dataMap, field)
+	}
+
+	apiKey := c.apiKey
+	if len(apiKey) == 0 {
+		envApiKey, found := os.LookupEnv(httputil.UptimeRobotApiKeyEnv)
+		if !found {
+			return nil, errors.New(httputil.ErrorApiKeyUndefined)
+		}
+		apiKey = envApiKey
+	}
+	apiUrl, found := os.LookupEnv(httputil.UptimeRobotApiUrlEnv)
+	if !found {
+		apiUrl = httputil.UptimeRobotApiUrl
+	}
+
+	form := url.Values{}
+	for key, value := range dataMap {
+		form.Add(key, formatValue(value))
+	}
+	form.Set(httputil.ApiKeyField, apiKey)
+	form.Set(httputil.FormatKeyField, "json")
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, apiUrl+endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add(httputil.ContentTypeField, httputil.FormUrlEncodedContentType)
+	req.Header.Add(httputil.CacheControlField, httputil.CacheControlValue)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusTooManyRequests {
+		return nil, &APIError{StatusCode: res.StatusCode, Body: string(body), RetryAfter: parseRetryAfter(res.Header.Get(retryAfterHeader))}
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, &APIError{StatusCode: res.StatusCode, Body: string(body)}
+	}
+
+	var resultMap map[string]interface{}
+	if err := json.Unmarshal(body, &resultMap); err != nil {
+		return nil, err
+	}
+	return resultMap, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "should return false for nil", err: nil, want: false},
+		{name: "should return true for server errors", err: &APIError{StatusCode: http.StatusServiceUnavailable}, want: true},
+		{name: "should return true for rate limiting", err: &APIError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "should return false for client errors", err: &APIError{StatusCode: http.StatusBadRequest}, want: false},
+		{name: "should return true for network errors", err: &url.Error{Op: "Post", URL: "http://localhost", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, want: true},
+		{name: "should return true for wrapped errors", err: fmt.Errorf("failed: %w", &APIError{StatusCode: http.StatusBadGateway}), want: true},
+		{name: "should return false for validation errors", err: errors.New("friendly_name needs to be specified"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransientError(tt.err); got != tt.want {
+				t.Errorf("IsTransientError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_apiClient_HttpInitiatePostRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		body          string
+		wantTransient bool
+		wantErr       bool
+	}{
+		{name: "should return result map", statusCode: http.StatusOK, body: `{"stat":"ok"}`},
+		{name: "should return transient error on server error", statusCode: http.StatusInternalServerError, body: "error", wantErr: true, wantTransient: true},
+		{name: "should return permanent error on client error", statusCode: http.StatusBadRequest, body: "error", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/"+httputil.GetMonitorsEndpoint || r.FormValue(httputil.ApiKeyField) != "key" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(tt.statusCode)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			t.Setenv(httputil.UptimeRobotApiKeyEnv, "key")
+			t.Setenv(httputil.UptimeRobotApiUrlEnv, server.URL+"/")
+
+			client := &apiClient{httpClient: server.Client(), ctx: context.Background()}
+			got, err := client.HttpInitiatePostRequest(httputil.GetMonitorsEndpoint, map[string]interface{}{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HttpInitiatePostRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if IsTransientError(err) != tt.wantTransient {
+				t.Errorf("IsTransientError() = %v, want %v", !tt.wantTransient, tt.wantTransient)
+			}
+			if !tt.wantErr && got["stat"] != "ok" {
+				t.Errorf("HttpInitiatePostRequest() = %v", got)
+			}
+		})
+	}
+}
+
+func Test_apiClient_HttpInitiatePostRequest_apiKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"stat":"ok","api_key":"` + r.FormValue(httputil.ApiKeyField) + `"}`))
+	}))
+	defer server.Close()
+	t.Setenv(httputil.UptimeRobotApiKeyEnv, "env-key")
+	t.Setenv(httputil.UptimeRobotApiUrlEnv, server.URL+"/")
+
+	tests := []struct {
+		name   string
+		apiKey string
+		want   string
+	}{
+		{name: "should send api key of the account", apiKey: "team-key", want: "team-key"},
+		{name: "should fall back to env api key", apiKey: "", want: "env-key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &apiClient{httpClient: server.Client(), ctx: context.Background(), apiKey: tt.apiKey}
+			got, err := client.HttpInitiatePostRequest(httputil.GetMonitorsEndpoint, map[string]interface{}{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got["api_key"] != tt.want {
+				t.Errorf("HttpInitiatePostRequest() api key = %v, want %v", got["api_key"], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// coalescer lets concurrent identical calls share the outcome of the first one, e.g. when several resources
+// request the same monitor while a restart re-reconciles every resource.
+type coalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done   chan struct{}
+	result interface{}
+	err    error
+}
+
+var apiCoalescer = &coalescer{calls: map[string]*coalescedCall{}}
+
+// do calls fn unless a call with the same key is in flight, in which case it waits for its outcome instead until ctx
+// is done.
+func (c *coalescer) do(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	if call, inFlight := c.calls[key]; inFlight {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.result, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &coalescedCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	call.result, call.err = fn()
+	c.mu.Lock()
+	delete(c.calls, key)
+	c.mu.Unlock()
+	close(call.done)
+	return call.result, call.err
+}
+
+// coalesceKey identifies the work of operation on the monitor of host with the annotations of the account of
+// apiKey, it is only equal for calls that would send the same requests. The api key is hashed with the rest.
+func coalesceKey(operation string, apiKey string, host string, annotations map[string]string) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n", operation, apiKey, host)
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s=%s\n", key, annotations[key])
+	}
+	return GetFriendlyName(annotations) + "/" + hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+func Test_coalescer_do(t *testing.T) {
+	c := &coalescer{calls: map[string]*coalescedCall{}}
+	var calls int32
+	var wg sync.WaitGroup
+	results := make([]interface{}, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = c.do(context.Background(), "key", func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(100 * time.Millisecond)
+				return "1", nil
+			})
+		}(i)
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("calls = %v, want 1", calls)
+	}
+	for _, result := range results {
+		if result != "1" {
+			t.Errorf("result = %v, want 1", result)
+		}
+	}
+
+	result, _ := c.do(context.Background(), "key", func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return "2", nil
+	})
+	if calls != 2 || result != "2" {
+		t.Errorf("calls = %v, result = %v, want 2 once the first call completed", calls, result)
+	}
+}
+
+func Test_coalesceKey(t *testing.T) {
+	annotations := map[string]string{GetUptimeRobotMonitorPrefix() + httputil.FriendlyNameField: "tester", GetUptimeRobotMonitorPrefix() + Interval: "300"}
+	key := coalesceKey("create", "", "https://a.local", annotations)
+	if key != coalesceKey("create", "", "https://a.local", map[string]string{GetUptimeRobotMonitorPrefix() + Interval: "300", GetUptimeRobotMonitorPrefix() + httputil.FriendlyNameField: "tester"}) {
+		t.Error("coalesceKey() differs for the same annotations")
+	}
+	if key == coalesceKey("create", "", "https://a.local", map[string]string{GetUptimeRobotMonitorPrefix() + httputil.FriendlyNameField: "tester"}) {
+		t.Error("coalesceKey() equal for different annotations")
+	}
+	if key == coalesceKey("delete", "", "https://a.local", annotations) {
+		t.Error("coalesceKey() equal for different operations")
+	}
+}
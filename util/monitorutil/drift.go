@@ -0,0 +1,138 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/service"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+// resolvedValues maps the names accepted in the annotations to the values returned by the api, it mirrors the
+// properties resolved by the tooling before a request is sent.
+var resolvedValues = map[string]map[string]string{
+	httputil.TypeField: {
+		"HTTP":      "1",
+		"HTTPS":     "1",
+		"Keyword":   "2",
+		"Ping":      "3",
+		"Port":      "4",
+		"Heartbeat": "5",
+	},
+	httputil.SubTypeField: {
+		"HTTP":  "1",
+		"HTTPS": "2",
+		"FTP":   "3",
+		"SMTP":  "4",
+		"POP3":  "5",
+		"IMAP":  "6",
+	},
+	httputil.KeywordTypeField: {
+		"exists":     "1",
+		"not exists": "2",
+	},
+	httputil.KeywordCaseTypeField: {
+		"case sensitive":   "0",
+		"case insensitive": "1",
+	},
+	httputil.HttpAuthTypeField: {
+		"Basic":           "1",
+		"Digest":          "2",
+		"HTTP Basic Auth": "1",
+	},
+}
+
+// Drift is the difference between the monitor described by the annotations and the monitor on UptimeRobot.
+type Drift struct {
+	// Missing is set when the monitor does not exist on UptimeRobot.
+	Missing bool
+	// Fields whose value on UptimeRobot differs from the annotations.
+	Fields []string
+	// MonitorId of the monitor on UptimeRobot, empty when it is missing.
+	MonitorId string
+}
+
+// HasDrift reports whether the monitor needs to be applied again.
+func (d *Drift) HasDrift() bool {
+	return d.Missing || len(d.Fields) > 0
+}
+
+func (d *Drift) String() string {
+	if d.Missing {
+		return "monitor is missing"
+	}
+	return fmt.Sprintf("fields %v drifted", d.Fields)
+}
+
+// GetMonitorDrift fetches the monitor by its friendly name and compares it to the annotations.
+func GetMonitorDrift(ctx context.Context, apiKey string, host string, ingressAnnotations map[string]string) (*Drift, error) {
+	drift, err := apiCoalescer.do(ctx, coalesceKey("drift", apiKey, host, ingressAnnotations), func() (interface{}, error) {
+		return getMonitorDrift(host, ingressAnnotations, newMonitorService(ctx, apiKey))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return drift.(*Drift), nil
+}
+
+func getMonitorDrift(host string, ingressAnnotations map[string]string, service service.IService) (*Drift, error) {
+	dataMap, err := buildDataMapFromAnnotations(ingressAnnotations)
+	if err != nil {
+		return nil, err
+	}
+	if _, exists := dataMap[Url]; !exists {
+		dataMap[Url] = host
+	}
+
+	remoteMonitor, err := findMonitor(fmt.Sprint(dataMap[httputil.FriendlyNameField]), service)
+	if err != nil {
+		return nil, err
+	}
+	if remoteMonitor == nil {
+		return &Drift{Missing: true}, nil
+	}
+	return compareMonitor(dataMap, remoteMonitor), nil
+}
+
+// compareMonitor compares the monitor on UptimeRobot to the monitor parameters of dataMap.
+func compareMonitor(dataMap map[string]interface{}, remoteMonitor map[string]interface{}) *Drift {
+	drift := &Drift{MonitorId: formatValue(remoteMonitor[httputil.IdField]), Fields: []string{}}
+	for key, value := range dataMap {
+		remoteValue, exists := remoteMonitor[key]
+		// fields that are not returned by the api e.g. alert_contacts can not be compared, the api only keeps
+		// the port of Port monitors.
+		if !exists || (key == httputil.PortField && !isPortMonitor(dataMap)) {
+			continue
+		}
+		desiredValue := fmt.Sprint(value)
+		if resolvedValue, exists := resolvedValues[key][desiredValue]; exists {
+			desiredValue = resolvedValue
+		}
+		if desiredValue != formatValue(remoteValue) {
+			drift.Fields = append(drift.Fields, key)
+		}
+	}
+	sort.Strings(drift.Fields)
+	return drift
+}
+
+func isPortMonitor(dataMap map[string]interface{}) bool {
+	return resolvedValues[httputil.TypeField][fmt.Sprint(dataMap[httputil.TypeField])] == resolvedValues[httputil.TypeField]["Port"]
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_getMonitorDrift(t *testing.T) {
+	prefix := GetUptimeRobotMonitorPrefix()
+	annotations := map[string]string{
+		GetUptimeRobotDomain():                        "true",
+		prefix + httputil.FriendlyNameField:           "example",
+		prefix + httputil.TypeField:                   "HTTP",
+		prefix + Interval:                             "300",
+		prefix + httputil.AlertContactsField:          "tester",
+		GetStatusAnnotationKey(IdAnnotation):          "1",
+		GetStatusAnnotationKey(LastAppliedAnnotation): "hash",
+	}
+	remoteMonitor := func(interval float64, url string) map[string]interface{} {
+		return map[string]interface{}{
+			httputil.MonitorsField: []interface{}{
+				map[string]interface{}{
+					httputil.IdField:           float64(777749809),
+					httputil.FriendlyNameField: "example",
+					httputil.TypeField:         float64(1),
+					httputil.UrlField:          url,
+					Interval:                   interval,
+					"status":                   float64(2),
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		result  map[string]interface{}
+		err     error
+		want    *Drift
+		wantErr bool
+	}{
+		{name: "should return no drift when monitor matches", result: remoteMonitor(300, "https://example.localhost"), want: &Drift{MonitorId: "777749809", Fields: []string{}}},
+		{name: "should return drifted fields", result: remoteMonitor(60, "https://other.localhost"), want: &Drift{MonitorId: "777749809", Fields: []string{Interval, Url}}},
+		{name: "should return missing monitor", result: map[string]interface{}{httputil.MonitorsField: []interface{}{}}, want: &Drift{Missing: true}},
+		{name: "should return error when lookup fails", result: map[string]interface{}{}, err: errors.New("some error"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testStruct := new(MockMonitorService)
+			testStruct.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, map[string]interface{}{httputil.SearchField: "example"}).Return(tt.result, tt.err)
+			got, err := getMonitorDrift("https://example.localhost", annotations, testStruct)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getMonitorDrift() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getMonitorDrift() = %v, want %v", got, tt.want)
+			}
+			if got != nil && got.HasDrift() != (tt.want.Missing || len(tt.want.Fields) > 0) {
+				t.Errorf("HasDrift() = %v", got.HasDrift())
+			}
+			testStruct.AssertCalled(t, "HttpInitiatePostRequest", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func Test_getMonitorDrift_port(t *testing.T) {
+	prefix := GetUptimeRobotMonitorPrefix()
+	tests := []struct {
+		name        string
+		monitorType string
+		want        []string
+	}{
+		{name: "should compare port of port monitors", monitorType: "Port", want: []string{httputil.PortField}},
+		{name: "should ignore port of http monitors", monitorType: "HTTP", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testStruct := new(MockMonitorService)
+			testStruct.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, mock.Anything).Return(map[string]interface{}{
+				httputil.MonitorsField: []interface{}{
+					map[string]interface{}{
+						httputil.IdField:           float64(1),
+						httputil.FriendlyNameField: "example",
+						httputil.PortField:         "",
+					},
+				},
+			}, nil)
+			got, err := getMonitorDrift("10.0.0.1", map[string]string{
+				prefix + httputil.FriendlyNameField: "example",
+				prefix + httputil.TypeField:         tt.monitorType,
+				prefix + httputil.PortField:         "5432",
+			}, testStruct)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Fields, tt.want) {
+				t.Errorf("getMonitorDrift() fields = %v, want %v", got.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formatValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "should format large numbers without exponent", value: float64(777749809), want: "777749809"},
+		{name: "should format decimals", value: 0.5, want: "0.5"},
+		{name: "should format strings", value: "HTTP", want: "HTTP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatValue(tt.value); got != tt.want {
+				t.Errorf("formatValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+// DefaultFriendlyNameTemplate names the monitors of resources with more than one host or path.
+const DefaultFriendlyNameTemplate = "{{.FriendlyName}}-{{.Host}}{{.Path}}"
+
+// FriendlyNameData is the data available to the friendly name template.
+type FriendlyNameData struct {
+	// FriendlyName configured in the annotations.
+	FriendlyName string
+	Host         string
+	// Path of the ingress rule monitored through the paths annotation, it is empty otherwise.
+	Path      string
+	Name      string
+	Namespace string
+}
+
+// ParseFriendlyNameTemplate parses the template used to give every host of a resource its own friendly name.
+func ParseFriendlyNameTemplate(text string) (*template.Template, error) {
+	return template.New("friendly-name").Option("missingkey=error").Parse(text)
+}
+
+// RenderFriendlyName renders the friendly name template with data.
+func RenderFriendlyName(tmpl *template.Template, data FriendlyNameData) (string, error) {
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// GetHostAnnotations returns the annotations of the monitor of host. Monitor annotations suffixed with "."+host
+// e.g. bennsimon.github.io/uptimerobot-monitor-friendly_name.example.com override the unsuffixed ones, the
+// annotations suffixed with any other host are dropped.
+func GetHostAnnotations(annotations map[string]string, host string) map[string]string {
+	uptimeRobotPrefix := GetUptimeRobotMonitorPrefix()
+	hostAnnotations := make(map[string]string, len(annotations))
+	overrides := map[string]string{}
+	for key, value := range annotations {
+		if !strings.HasPrefix(key, uptimeRobotPrefix) {
+			hostAnnotations[key] = value
+			continue
+		}
+		parameter, parameterHost, found := strings.Cut(strings.TrimPrefix(key, uptimeRobotPrefix), ".")
+		if !found {
+			hostAnnotations[key] = value
+		} else if parameterHost == host {
+			overrides[uptimeRobotPrefix+parameter] = value
+		}
+	}
+	for key, value := range overrides {
+		hostAnnotations[key] = value
+	}
+	return hostAnnotations
+}
+
+// HasHostFriendlyName reports whether the friendly name of the monitor of host is overridden in the annotations.
+func HasHostFriendlyName(annotations map[string]string, host string) bool {
+	_, exists := annotations[GetUptimeRobotMonitorPrefix()+httputil.FriendlyNameField+"."+host]
+	return exists
+}
+
+// SplitTarget splits the target of a monitor of the form <host>[/<path>] into its host and path, the path keeps
+// its leading slash.
+func SplitTarget(target string) (string, string) {
+	if index := strings.Index(target, "/"); index >= 0 {
+		return target[:index], target[index:]
+	}
+	return target, ""
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+func TestGetHostAnnotations(t *testing.T) {
+	prefix := GetUptimeRobotMonitorPrefix()
+	annotations := map[string]string{
+		GetUptimeRobotDomain():                               "true",
+		"kubernetes.io/ingress.class":                        "nginx",
+		prefix + httputil.FriendlyNameField:                  "tester",
+		prefix + Interval:                                    "300",
+		prefix + httputil.FriendlyNameField + ".a.localhost": "custom",
+		prefix + Interval + ".b.localhost":                   "60",
+	}
+	tests := []struct {
+		name string
+		host string
+		want map[string]string
+	}{
+		{name: "should override parameters of the host", host: "a.localhost", want: map[string]string{
+			GetUptimeRobotDomain():              "true",
+			"kubernetes.io/ingress.class":       "nginx",
+			prefix + httputil.FriendlyNameField: "custom",
+			prefix + Interval:                   "300",
+		}},
+		{name: "should drop parameters of other hosts", host: "c.localhost", want: map[string]string{
+			GetUptimeRobotDomain():              "true",
+			"kubernetes.io/ingress.class":       "nginx",
+			prefix + httputil.FriendlyNameField: "tester",
+			prefix + Interval:                   "300",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetHostAnnotations(annotations, tt.host); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetHostAnnotations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderFriendlyName(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{name: "should render default template", template: DefaultFriendlyNameTemplate, want: "tester-a.localhost"},
+		{name: "should render resource fields", template: "{{.Namespace}}/{{.Name}} {{.Host}}", want: "default/ingress a.localhost"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseFriendlyNameTemplate(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := RenderFriendlyName(tmpl, FriendlyNameData{FriendlyName: "tester", Host: "a.localhost", Name: "ingress", Namespace: "default"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RenderFriendlyName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RenderFriendlyName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFriendlyNameTemplateShouldReturnErrorOnInvalidTemplate(t *testing.T) {
+	if _, err := ParseFriendlyNameTemplate("{{.FriendlyName"); err == nil {
+		t.Errorf("got %v ,  want error", err)
+	}
+}
+
+func TestSplitTarget(t *testing.T) {
+	tests := []struct {
+		target   string
+		wantHost string
+		wantPath string
+	}{
+		{target: "a.localhost", wantHost: "a.localhost", wantPath: ""},
+		{target: "a.localhost/", wantHost: "a.localhost", wantPath: "/"},
+		{target: "a.localhost/api/healthz", wantHost: "a.localhost", wantPath: "/api/healthz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			host, path := SplitTarget(tt.target)
+			if host != tt.wantHost || path != tt.wantPath {
+				t.Errorf("SplitTarget() = %v, %v, want %v, %v", host, path, tt.wantHost, tt.wantPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-operator/util/fakeuptimerobot"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/service/monitor"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+// TestMonitorLifecycle runs the monitor functions against the fake api to cover the http contract of the tooling.
+func TestMonitorLifecycle(t *testing.T) {
+	server := fakeuptimerobot.NewServer("key")
+	defer server.Close()
+	t.Setenv(httputil.UptimeRobotApiKeyEnv, "key")
+	t.Setenv(httputil.UptimeRobotApiUrlEnv, server.BaseURL())
+	t.Setenv(monitor.MonitorAlertContactsResolveByFriendlyNameEnv, "true")
+	alertContactId := server.AddAlertContact("tester")
+
+	prefix := GetUptimeRobotMonitorPrefix()
+	annotations := map[string]string{
+		GetUptimeRobotDomain():               "true",
+		prefix + httputil.FriendlyNameField:  "example",
+		prefix + httputil.TypeField:          "HTTP",
+		prefix + Interval:                    "300",
+		prefix + httputil.AlertContactsField: "tester",
+	}
+
+	id, err := CreateMonitor(context.Background(), "", "https://example.localhost", annotations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, found := server.FindMonitor("example")
+	if !found || strconv.Itoa(created.Id) != id {
+		t.Fatalf("CreateMonitor() = %v, monitors %v", id, server.Monitors())
+	}
+	if created.Url() != "https://example.localhost" || created.Fields[httputil.TypeField] != "1" || created.Fields[httputil.AlertContactsField] != alertContactId {
+		t.Errorf("created monitor = %v", created.Fields)
+	}
+
+	drift, err := GetMonitorDrift(context.Background(), "", "https://example.localhost", annotations)
+	if err != nil || drift.HasDrift() || drift.MonitorId != id {
+		t.Errorf("GetMonitorDrift() = %v, %v", drift, err)
+	}
+
+	annotations[prefix+Interval] = "60"
+	drift, err = GetMonitorDrift(context.Background(), "", "https://example.localhost", annotations)
+	if err != nil || !drift.HasDrift() {
+		t.Errorf("GetMonitorDrift() = %v, %v, want drift", drift, err)
+	}
+	if updatedId, err := CreateMonitor(context.Background(), "", "https://example.localhost", annotations); err != nil || updatedId != id {
+		t.Fatalf("CreateMonitor() = %v, %v, want update of %v", updatedId, err, id)
+	}
+	if updated, _ := server.FindMonitor("example"); updated.Fields[Interval] != "60" {
+		t.Errorf("updated monitor = %v", updated.Fields)
+	}
+
+	annotations[prefix+httputil.FriendlyNameField] = "renamed"
+	if renamedId, err := UpdateMonitor(context.Background(), "", id, "https://example.localhost", annotations); err != nil || renamedId != id {
+		t.Fatalf("UpdateMonitor() = %v, %v, want rename of %v", renamedId, err, id)
+	}
+	if renamed, found := server.FindMonitor("renamed"); !found || strconv.Itoa(renamed.Id) != id || len(server.Monitors()) != 1 {
+		t.Errorf("monitors = %v, want %v renamed", server.Monitors(), id)
+	}
+	annotations[prefix+httputil.FriendlyNameField] = "example"
+	if _, err := UpdateMonitor(context.Background(), "", id, "https://example.localhost", annotations); err != nil {
+		t.Fatal(err)
+	}
+
+	monitors, err := ListMonitors(context.Background(), "", "exam")
+	if err != nil || len(monitors) != 1 || monitors[0].Id != id {
+		t.Errorf("ListMonitors() = %v, %v", monitors, err)
+	}
+
+	if err := DeleteMonitor(context.Background(), "", "https://example.localhost", annotations); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.Monitors()) != 0 {
+		t.Errorf("monitors = %v, want none", server.Monitors())
+	}
+	if err := DeleteMonitor(context.Background(), "", "https://example.localhost", annotations); !IsMonitorNotFound(err) {
+		t.Errorf("DeleteMonitor() = %v, want not found", err)
+	}
+}
+
+func TestMonitorLifecycle_apiKey(t *testing.T) {
+	server := fakeuptimerobot.NewServer("team-key")
+	defer server.Close()
+	t.Setenv(httputil.UptimeRobotApiKeyEnv, "key")
+	t.Setenv(httputil.UptimeRobotApiUrlEnv, server.BaseURL())
+
+	annotations := map[string]string{
+		GetUptimeRobotMonitorPrefix() + httputil.FriendlyNameField: "example",
+		GetUptimeRobotMonitorPrefix() + httputil.TypeField:         "HTTP",
+	}
+	if _, err := CreateMonitor(context.Background(), "", "https://example.localhost", annotations); err == nil {
+		t.Errorf("CreateMonitor() with env api key succeeded on another account")
+	}
+	if _, err := CreateMonitor(context.Background(), "team-key", "https://example.localhost", annotations); err != nil {
+		t.Errorf("CreateMonitor() = %v", err)
+	}
+}
+
+// TestMonitorLifecycle_accountRequests makes sure that every request for the monitors of an account is sent with its
+// api key rather than the env one, including the lookups sent outside of the tooling.
+func TestMonitorLifecycle_accountRequests(t *testing.T) {
+	server := fakeuptimerobot.NewServer("team-key")
+	defer server.Close()
+	t.Setenv(httputil.UptimeRobotApiKeyEnv, "env-key")
+	t.Setenv(httputil.UptimeRobotApiUrlEnv, server.BaseURL())
+
+	annotations := map[string]string{
+		GetUptimeRobotMonitorPrefix() + httputil.FriendlyNameField: "example",
+		GetUptimeRobotMonitorPrefix() + httputil.TypeField:         "HTTP",
+	}
+	id, err := CreateMonitor(context.Background(), "team-key", "https://example.localhost", annotations)
+	if err != nil || len(id) == 0 {
+		t.Fatalf("CreateMonitor() = %v, %v", id, err)
+	}
+	if drift, err := GetMonitorDrift(context.Background(), "team-key", "https://example.localhost", annotations); err != nil || drift.Missing {
+		t.Errorf("GetMonitorDrift() = %v, %v", drift, err)
+	}
+	if adopted, err := FindMonitorToAdopt(context.Background(), "team-key", id, "https://example.localhost", annotations); err != nil || adopted.MonitorId != id {
+		t.Errorf("FindMonitorToAdopt() = %v, %v", adopted, err)
+	}
+	if updatedId, err := UpdateMonitor(context.Background(), "team-key", id, "https://example.localhost", annotations); err != nil || updatedId != id {
+		t.Errorf("UpdateMonitor() = %v, %v", updatedId, err)
+	}
+	if monitors, err := ListMonitors(context.Background(), "team-key", "exam"); err != nil || len(monitors) != 1 {
+		t.Errorf("ListMonitors() = %v, %v", monitors, err)
+	}
+	if err := SetMonitorPausedById(context.Background(), "team-key", id, true); err != nil {
+		t.Errorf("SetMonitorPausedById() = %v", err)
+	}
+	if statuses, err := GetMonitorStatuses(context.Background(), "team-key", []string{id}); err != nil || len(statuses) != 1 {
+		t.Errorf("GetMonitorStatuses() = %v, %v", statuses, err)
+	}
+	if err := DeleteMonitorById(context.Background(), "team-key", id); err != nil {
+		t.Errorf("DeleteMonitorById() = %v", err)
+	}
+
+	apiKeys := server.ReceivedAPIKeys()
+	if len(apiKeys) != 1 || apiKeys["team-key"] == 0 {
+		t.Errorf("requests by api key = %v, want team-key only", apiKeys)
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Error classes of the failed requests to the UptimeRobot api.
+const (
+	ErrorClassRateLimited = "rate_limited"
+	ErrorClassServer      = "server_error"
+	ErrorClassClient      = "client_error"
+	ErrorClassNetwork     = "network"
+	ErrorClassRejected    = "rejected"
+	ErrorClassOther       = "other"
+)
+
+var (
+	apiRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "uptimerobot_operator_api_requests_total",
+		Help: "Requests sent to the UptimeRobot api by endpoint, e.g. newMonitor, editMonitor or deleteMonitor.",
+	}, []string{"endpoint"})
+	apiRequestFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "uptimerobot_operator_api_request_failures_total",
+		Help: "Failed requests to the UptimeRobot api by endpoint and error class.",
+	}, []string{"endpoint", "class"})
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "uptimerobot_operator_api_request_duration_seconds",
+		Help:    "Latency of the requests to the UptimeRobot api by endpoint.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"endpoint"})
+	apiRateLimitedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "uptimerobot_operator_api_rate_limited_total",
+		Help: "Requests to the UptimeRobot api answered with 429 Too Many Requests.",
+	})
+)
+
+// APICollectors returns the collectors of the metrics of the requests to the UptimeRobot api, they are registered
+// by the caller e.g. on the metrics registry of the manager.
+func APICollectors() []prometheus.Collector {
+	return []prometheus.Collector{apiRequestsTotal, apiRequestFailuresTotal, apiRequestDuration, apiRateLimitedTotal}
+}
+
+// observeRequest records the outcome of a request to endpoint started at start, rejected reports whether the api
+// answered but refused the request.
+func observeRequest(endpoint string, start time.Time, err error, rejected bool) {
+	apiRequestsTotal.WithLabelValues(endpoint).Inc()
+	apiRequestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+	if err == nil && !rejected {
+		return
+	}
+	class := ErrorClassRejected
+	if err != nil {
+		class = ErrorClass(err)
+	}
+	if class == ErrorClassRateLimited {
+		apiRateLimitedTotal.Inc()
+	}
+	apiRequestFailuresTotal.WithLabelValues(endpoint, class).Inc()
+}
+
+// ErrorClass classifies err of a failed request to the UptimeRobot api.
+func ErrorClass(err error) string {
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		switch {
+		case apiError.StatusCode == http.StatusTooManyRequests:
+			return ErrorClassRateLimited
+		case apiError.StatusCode >= http.StatusInternalServerError:
+			return ErrorClassServer
+		default:
+			return ErrorClassClient
+		}
+	}
+	var netError net.Error
+	if errors.As(err, &netError) {
+		return ErrorClassNetwork
+	}
+	return ErrorClassOther
+}
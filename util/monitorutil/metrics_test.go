@@ -0,0 +1,71 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "should classify rate limiting", err: &APIError{StatusCode: http.StatusTooManyRequests}, want: ErrorClassRateLimited},
+		{name: "should classify server errors", err: &APIError{StatusCode: http.StatusBadGateway}, want: ErrorClassServer},
+		{name: "should classify client errors", err: &APIError{StatusCode: http.StatusUnauthorized}, want: ErrorClassClient},
+		{name: "should classify network errors", err: &url.Error{Op: "Post", URL: "http://localhost", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, want: ErrorClassNetwork},
+		{name: "should classify other errors", err: errors.New("some error"), want: ErrorClassOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorClass(tt.err); got != tt.want {
+				t.Errorf("ErrorClass() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_observeRequest(t *testing.T) {
+	const endpoint = "observeRequestTest"
+	observeRequest(endpoint, time.Now(), nil, false)
+	observeRequest(endpoint, time.Now(), nil, true)
+	observeRequest(endpoint, time.Now(), &APIError{StatusCode: http.StatusTooManyRequests}, false)
+	rateLimited := testutil.ToFloat64(apiRateLimitedTotal)
+	observeRequest(endpoint, time.Now(), &APIError{StatusCode: http.StatusTooManyRequests}, false)
+
+	if got := testutil.ToFloat64(apiRequestsTotal.WithLabelValues(endpoint)); got != 4 {
+		t.Errorf("requests = %v, want 4", got)
+	}
+	if got := testutil.ToFloat64(apiRequestFailuresTotal.WithLabelValues(endpoint, ErrorClassRejected)); got != 1 {
+		t.Errorf("rejected failures = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(apiRequestFailuresTotal.WithLabelValues(endpoint, ErrorClassRateLimited)); got != 2 {
+		t.Errorf("rate limited failures = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(apiRateLimitedTotal) - rateLimited; got != 1 {
+		t.Errorf("rate limited = %v, want 1", got)
+	}
+}
@@ -1,43 +1,257 @@
 package monitorutil
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/bennsimon/uptimerobot-tooling/pkg/model"
 	"github.com/bennsimon/uptimerobot-tooling/pkg/service"
 	"github.com/bennsimon/uptimerobot-tooling/pkg/service/monitor"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
 	"os"
+	"strconv"
 	"strings"
 )
 
 const (
 	DomainPrefixEnv  = "DOMAIN_PREFIX"
 	Url              = "url"
+	Interval         = "interval"
+	Timeout          = "timeout"
 	AnnotationPrefix = "uptimerobot-monitor"
 )
 
-func DeleteMonitor(host string, ingressAnnotations map[string]string) error {
-	return executeMonitorAction(host, ingressAnnotations, model.Delete, monitor.New())
+// Annotations written by the operator to report the state of the monitor, they are never sent to UptimeRobot.
+const (
+	StatusAnnotation     = "status"
+	IdAnnotation         = "id"
+	LastSyncedAnnotation = "last-synced"
+	// LastAppliedAnnotation holds a hash of the monitor configuration last applied to UptimeRobot.
+	LastAppliedAnnotation = "last-applied"
+	// ManagedMonitorsAnnotation maps the url of every monitor created for the resource to its id as JSON, so that
+	// the monitors of the hosts removed from the resource can be found.
+	ManagedMonitorsAnnotation = "managed-monitors"
+)
+
+// ApiKeySecretAnnotation references the Secret holding the api key of the UptimeRobot account of the monitors as
+// <secret name>/<key>, it is never sent to UptimeRobot.
+const ApiKeySecretAnnotation = "api-key-secret"
+
+// TemplateAnnotation names the MonitorTemplate, or else ClusterMonitorTemplate, supplying the default monitor
+// parameters of the resource, it is never sent to UptimeRobot.
+const TemplateAnnotation = "template"
+
+// PathAnnotation is the path appended to the host in the url of the monitor e.g. /healthz, it is never sent to
+// UptimeRobot.
+const PathAnnotation = "path"
+
+// PathsAnnotation is a regular expression selecting the paths of the ingress rules to monitor, every matching path
+// of a host gets its own monitor. It is never sent to UptimeRobot.
+const PathsAnnotation = "paths"
+
+// OnDisableAnnotation is the policy applied to the monitors of a resource once its monitoring is disabled, either
+// OnDisableDelete or OnDisablePause. It is never sent to UptimeRobot.
+const OnDisableAnnotation = "on-disable"
+
+// AdoptAnnotation adopts an existing monitor found by its url, or else its friendly name, when the resource has no
+// monitor yet: "true" adopts it, AdoptPreview only reports how adopting it would change it. AdoptIdAnnotation adopts
+// the monitor with the id instead. They are never sent to UptimeRobot.
+const (
+	AdoptAnnotation   = "adopt"
+	AdoptIdAnnotation = "adopt-id"
+	AdoptPreview      = "preview"
+)
+
+// Policies applied to the monitors of a resource whose monitoring is disabled.
+const (
+	OnDisableDelete = "delete"
+	OnDisablePause  = "pause"
+)
+
+const (
+	StatusSynced = "Synced"
+	StatusFailed = "Failed"
+	// StatusPaused is the status of the resources whose monitors were paused once their monitoring was disabled.
+	StatusPaused = "Paused"
+)
+
+var statusAnnotations = map[string]bool{
+	StatusAnnotation:          true,
+	IdAnnotation:              true,
+	LastSyncedAnnotation:      true,
+	LastAppliedAnnotation:     true,
+	ManagedMonitorsAnnotation: true,
 }
 
-func CreateMonitor(host string, ingressAnnotations map[string]string) error {
-	return executeMonitorAction(host, ingressAnnotations, model.Update, monitor.New())
+// operatorAnnotations configure the operator rather than the monitor.
+var operatorAnnotations = map[string]bool{
+	ApiKeySecretAnnotation: true,
+	TemplateAnnotation:     true,
+	PathAnnotation:         true,
+	PathsAnnotation:        true,
+	OnDisableAnnotation:    true,
+	AdoptAnnotation:        true,
+	AdoptIdAnnotation:      true,
 }
 
-func executeMonitorAction(host string, ingressAnnotations map[string]string, action model.Args, service service.IService) error {
-	annotations, err := buildDataMapFromAnnotations(ingressAnnotations)
+// isOperatorSetting reports whether the parameter configures the operator for the whole resource. Unlike the other
+// operator annotations the path is part of each monitor, it can be templated and set by monitor templates.
+func isOperatorSetting(parameter string) bool {
+	return operatorAnnotations[parameter] && parameter != PathAnnotation
+}
+
+// DeleteMonitor deletes the monitor, apiKey selects the UptimeRobot account, the UPTIME_ROBOT_API_KEY env var
+// is used when empty.
+func DeleteMonitor(ctx context.Context, apiKey string, host string, ingressAnnotations map[string]string) error {
+	_, err := apiCoalescer.do(ctx, coalesceKey("delete", apiKey, host, ingressAnnotations), func() (interface{}, error) {
+		return executeMonitorAction(host, ingressAnnotations, model.Delete, newMonitorService(ctx, apiKey))
+	})
+	return err
+}
+
+// CreateMonitor creates or updates the monitor and returns its id. Concurrent identical calls are coalesced.
+func CreateMonitor(ctx context.Context, apiKey string, host string, ingressAnnotations map[string]string) (string, error) {
+	id, err := apiCoalescer.do(ctx, coalesceKey("create", apiKey, host, ingressAnnotations), func() (interface{}, error) {
+		return createMonitor(host, ingressAnnotations, newMonitorService(ctx, apiKey))
+	})
+	if err != nil {
+		return "", err
+	}
+	return id.(string), nil
+}
+
+// UpdateMonitor updates the monitor with the id in place, e.g. renaming it, and returns its id. The monitor is created
+// or updated by its friendly name as by CreateMonitor when no monitor has the id. Concurrent identical calls are
+// coalesced.
+func UpdateMonitor(ctx context.Context, apiKey string, id string, host string, ingressAnnotations map[string]string) (string, error) {
+	updatedId, err := apiCoalescer.do(ctx, coalesceKey("update "+id, apiKey, host, ingressAnnotations), func() (interface{}, error) {
+		return updateMonitor(id, host, ingressAnnotations, newMonitorService(ctx, apiKey))
+	})
 	if err != nil {
-		return err
+		return "", err
+	}
+	return updatedId.(string), nil
+}
+
+func updateMonitor(id string, host string, ingressAnnotations map[string]string, service service.IService) (string, error) {
+	existing, err := findMonitorById(id, service)
+	if err != nil {
+		return "", err
+	}
+	if existing == nil {
+		return createMonitor(host, ingressAnnotations, service)
 	}
+	dataMap, err := executeMonitorActionById(id, host, ingressAnnotations, model.Update, service)
+	if err != nil {
+		return "", err
+	}
+	// the tooling replaces the monitor when its type changes, the new monitor has another id.
+	if dataMap[httputil.TypeField] != nil && fmt.Sprint(dataMap[httputil.TypeField]) != formatValue(existing[httputil.TypeField]) {
+		return findMonitorId(fmt.Sprint(dataMap[httputil.FriendlyNameField]), service)
+	}
+	return id, nil
+}
+
+func createMonitor(host string, ingressAnnotations map[string]string, service service.IService) (string, error) {
+	dataMap, err := executeMonitorAction(host, ingressAnnotations, model.Update, service)
+	if err != nil {
+		return "", err
+	}
+	// the tooling only resolves the id when an existing monitor is updated.
+	if id, exists := dataMap[httputil.IdField]; exists && id != nil {
+		return formatValue(id), nil
+	}
+	return findMonitorId(fmt.Sprint(dataMap[httputil.FriendlyNameField]), service)
+}
 
-	if _, exists := annotations[Url]; !exists {
-		annotations[Url] = host
+func executeMonitorAction(host string, ingressAnnotations map[string]string, action model.Args, service service.IService) (map[string]interface{}, error) {
+	return executeMonitorActionById("", host, ingressAnnotations, action, service)
+}
+
+// executeMonitorActionById executes the action on the monitor with the id, or else the monitor with the friendly
+// name of the annotations when id is empty.
+func executeMonitorActionById(id string, host string, ingressAnnotations map[string]string, action model.Args, service service.IService) (map[string]interface{}, error) {
+	annotations, err := BuildMonitorPayload(id, host, ingressAnnotations)
+	if err != nil {
+		return nil, err
 	}
 
 	resultArrayMap := service.HandleRequest([]map[string]interface{}{annotations}, action)
 	if resultArrayMap != nil && len(resultArrayMap) > 0 && resultArrayMap[0] != nil && resultArrayMap[0][model.ErrorResultField] != nil {
-		return resultArrayMap[0][model.ErrorResultField].(error)
+		return nil, resultArrayMap[0][model.ErrorResultField].(error)
+	}
+	return annotations, nil
+}
+
+// BuildMonitorPayload builds the parameters sent to UptimeRobot for the monitor of the host with the id, the url
+// defaults to the host and the monitor is matched by its friendly name when id is empty.
+func BuildMonitorPayload(id string, host string, ingressAnnotations map[string]string) (map[string]interface{}, error) {
+	dataMap, err := buildDataMapFromAnnotations(ingressAnnotations)
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > 0 {
+		dataMap[httputil.IdField] = id
+	}
+	if _, exists := dataMap[Url]; !exists {
+		dataMap[Url] = host
+	}
+	return dataMap, nil
+}
+
+// findMonitorId looks up the id of the monitor with the exact friendly name, it returns an empty id if none matches.
+func findMonitorId(friendlyName string, service service.IService) (string, error) {
+	_monitor, err := findMonitor(friendlyName, service)
+	if err != nil || _monitor == nil {
+		return "", err
+	}
+	return formatValue(_monitor[httputil.IdField]), nil
+}
+
+// findMonitorById fetches the monitor with the id, it returns nil if none matches. A failed request is left to the
+// update by friendly name to report.
+func findMonitorById(id string, service service.IService) (map[string]interface{}, error) {
+	resultMap, err := service.HttpInitiatePostRequest(httputil.GetMonitorsEndpoint, map[string]interface{}{
+		httputil.MonitorsField: id,
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	monitors, _ := resultMap[httputil.MonitorsField].([]interface{})
+	for _, m := range monitors {
+		_monitor, ok := m.(map[string]interface{})
+		if ok && formatValue(_monitor[httputil.IdField]) == id {
+			return _monitor, nil
+		}
+	}
+	return nil, nil
+}
+
+// findMonitor fetches the monitor with the exact friendly name, it returns nil if none matches.
+func findMonitor(friendlyName string, service service.IService) (map[string]interface{}, error) {
+	resultMap, err := service.HttpInitiatePostRequest(httputil.GetMonitorsEndpoint, map[string]interface{}{
+		httputil.SearchField: friendlyName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	monitors, _ := resultMap[httputil.MonitorsField].([]interface{})
+	for _, m := range monitors {
+		_monitor, ok := m.(map[string]interface{})
+		if ok && fmt.Sprint(_monitor[httputil.FriendlyNameField]) == friendlyName && _monitor[httputil.IdField] != nil {
+			return _monitor, nil
+		}
+	}
+	return nil, nil
+}
+
+// formatValue formats values decoded from the api, numbers are decoded as float64 which fmt would print
+// in exponent form e.g. ids.
+func formatValue(value interface{}) string {
+	if number, ok := value.(float64); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
 }
 
 func buildDataMapFromAnnotations(ingressAnnotations map[string]string) (map[string]interface{}, error) {
@@ -50,6 +264,9 @@ func buildDataMapFromAnnotations(ingressAnnotations map[string]string) (map[stri
 	for key, value := range ingressAnnotations {
 		if strings.HasPrefix(key, uptimeRobotPrefix) {
 			_Key := strings.TrimPrefix(key, uptimeRobotPrefix)
+			if statusAnnotations[_Key] || operatorAnnotations[_Key] {
+				continue
+			}
 			dataMap[_Key] = value
 		}
 	}
@@ -69,6 +286,32 @@ func GetUptimeRobotMonitorPrefix() string {
 	return GetUptimeRobotDomain() + "-"
 }
 
+// GetStatusAnnotationKey returns the full annotation key of a status annotation e.g. StatusAnnotation.
+func GetStatusAnnotationKey(name string) string {
+	return GetUptimeRobotMonitorPrefix() + name
+}
+
+// IsStatusAnnotation reports whether the annotation key is one of the status annotations written by the operator.
+func IsStatusAnnotation(key string) bool {
+	uptimeRobotPrefix := GetUptimeRobotMonitorPrefix()
+	return strings.HasPrefix(key, uptimeRobotPrefix) && statusAnnotations[strings.TrimPrefix(key, uptimeRobotPrefix)]
+}
+
+// GetUptimeRobotFinalizer returns the finalizer added to resources whose monitors are managed by the operator.
+func GetUptimeRobotFinalizer() string {
+	return GetUptimeRobotDomain()
+}
+
+// IsMonitorNotFound reports whether err was returned because the monitor does not exist on UptimeRobot.
+func IsMonitorNotFound(err error) bool {
+	return err != nil && err.Error() == monitor.MsgMonitorDoesNotExist
+}
+
+// GetFriendlyName returns the friendly_name configured in the annotations.
+func GetFriendlyName(annotations map[string]string) string {
+	return annotations[GetUptimeRobotMonitorPrefix()+httputil.FriendlyNameField]
+}
+
 func getUptimeRobotDomain() string {
 	return os.Getenv(DomainPrefixEnv)
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"github.com/bennsimon/uptimerobot-tooling/pkg/model"
 	"github.com/bennsimon/uptimerobot-tooling/pkg/service"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
 	"github.com/stretchr/testify/mock"
 	"reflect"
 	"testing"
@@ -18,22 +19,27 @@ func (m *MockMonitorService) HandleRequest(dataMapInterface []map[string]interfa
 	return m.Called(dataMapInterface, action).Get(0).([]map[string]interface{})
 }
 
+func (m *MockMonitorService) HttpInitiatePostRequest(endpoint string, dataMap map[string]interface{}) (map[string]interface{}, error) {
+	args := m.Called(endpoint, dataMap)
+	return args.Get(0).(map[string]interface{}), args.Error(1)
+}
+
 func TestExecuteMonitorActionShouldReturnErrorWhenIngressAnnotationIsNil(t *testing.T) {
-	err := executeMonitorAction("", nil, model.Update, &service.NopService{})
+	_, err := executeMonitorAction("", nil, model.Update, &service.NopService{})
 	if err == nil {
 		t.Errorf("got %v ,  want %v", nil, err)
 	}
 }
 
 func TestExecuteMonitorActionShouldReturnErrorWhenAnnotationsIsEmpty(t *testing.T) {
-	err := executeMonitorAction("", map[string]string{}, model.Update, &service.NopService{})
+	_, err := executeMonitorAction("", map[string]string{}, model.Update, &service.NopService{})
 	if err == nil {
 		t.Errorf("got %v ,  want %v", nil, err)
 	}
 }
 
 func TestExecuteMonitorActionShouldReturnNil(t *testing.T) {
-	err := executeMonitorAction("", map[string]string{
+	_, err := executeMonitorAction("", map[string]string{
 		GetUptimeRobotDomain(): "true",
 	}, model.Update, &service.NopService{})
 	if err != nil {
@@ -46,7 +52,7 @@ func TestExecuteMonitorActionShouldErrorOnResult(t *testing.T) {
 	testStruct.On("HandleRequest", mock.IsType([]map[string]interface{}{}), mock.IsType(model.Args(""))).Return([]map[string]interface{}{
 		{model.ErrorResultField: errors.New("some error")},
 	})
-	err := executeMonitorAction("", map[string]string{
+	_, err := executeMonitorAction("", map[string]string{
 		GetUptimeRobotDomain(): "true",
 	}, model.Update, testStruct)
 	if err == nil {
@@ -72,6 +78,29 @@ func Test_buildDataMapFromAnnotations(t *testing.T) {
 		}}, want: map[string]interface{}{
 			"type": "HTTP",
 		}, wantErr: false},
+		{name: "should skip status annotations", args: args{ingressAnnotations: map[string]string{
+			GetUptimeRobotDomain():                       "true",
+			GetUptimeRobotMonitorPrefix() + "type":       "HTTP",
+			GetStatusAnnotationKey(StatusAnnotation):     StatusSynced,
+			GetStatusAnnotationKey(IdAnnotation):         "1",
+			GetStatusAnnotationKey(LastSyncedAnnotation): "2022-11-24T19:30:43Z",
+		}}, want: map[string]interface{}{
+			"type": "HTTP",
+		}, wantErr: false},
+		{name: "should skip api key secret annotation", args: args{ingressAnnotations: map[string]string{
+			GetUptimeRobotMonitorPrefix() + "type":                 "HTTP",
+			GetUptimeRobotMonitorPrefix() + ApiKeySecretAnnotation: "uptimerobot/api-key",
+		}}, want: map[string]interface{}{
+			"type": "HTTP",
+		}, wantErr: false},
+		{name: "should skip operator annotations", args: args{ingressAnnotations: map[string]string{
+			GetUptimeRobotMonitorPrefix() + "type":             "HTTP",
+			GetUptimeRobotMonitorPrefix() + TemplateAnnotation: "standard-http",
+			GetUptimeRobotMonitorPrefix() + PathAnnotation:     "/healthz",
+			GetUptimeRobotMonitorPrefix() + PathsAnnotation:    "healthz$",
+		}}, want: map[string]interface{}{
+			"type": "HTTP",
+		}, wantErr: false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -86,3 +115,155 @@ func Test_buildDataMapFromAnnotations(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildMonitorPayload(t *testing.T) {
+	type args struct {
+		id                 string
+		host               string
+		ingressAnnotations map[string]string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{name: "should default the url to the host", args: args{host: "https://a.local", ingressAnnotations: map[string]string{
+			GetUptimeRobotMonitorPrefix() + "type": "HTTP",
+		}}, want: map[string]interface{}{
+			"type": "HTTP",
+			Url:    "https://a.local",
+		}},
+		{name: "should keep the url of the annotations and set the id", args: args{id: "1", host: "https://a.local", ingressAnnotations: map[string]string{
+			GetUptimeRobotMonitorPrefix() + Url: "https://a.local/healthz",
+		}}, want: map[string]interface{}{
+			httputil.IdField: "1",
+			Url:              "https://a.local/healthz",
+		}},
+		{name: "should return error if annotations are empty", args: args{host: "https://a.local"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildMonitorPayload(tt.args.id, tt.args.host, tt.args.ingressAnnotations)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BuildMonitorPayload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildMonitorPayload() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_createMonitor(t *testing.T) {
+	annotations := map[string]string{
+		GetUptimeRobotDomain(): "true",
+		GetUptimeRobotMonitorPrefix() + httputil.FriendlyNameField: "example",
+	}
+	tests := []struct {
+		name    string
+		setup   func(m *MockMonitorService)
+		want    string
+		wantErr bool
+	}{
+		{name: "should return id resolved while updating", setup: func(m *MockMonitorService) {
+			m.On("HandleRequest", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				args.Get(0).([]map[string]interface{})[0][httputil.IdField] = 1
+			}).Return([]map[string]interface{}{})
+		}, want: "1"},
+		{name: "should look up id of created monitor", setup: func(m *MockMonitorService) {
+			m.On("HandleRequest", mock.Anything, mock.Anything).Return([]map[string]interface{}{})
+			m.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, map[string]interface{}{httputil.SearchField: "example"}).Return(map[string]interface{}{
+				httputil.MonitorsField: []interface{}{
+					map[string]interface{}{httputil.FriendlyNameField: "example-2", httputil.IdField: 2},
+					map[string]interface{}{httputil.FriendlyNameField: "example", httputil.IdField: 1},
+				},
+			}, nil)
+		}, want: "1"},
+		{name: "should return error if lookup fails", setup: func(m *MockMonitorService) {
+			m.On("HandleRequest", mock.Anything, mock.Anything).Return([]map[string]interface{}{})
+			m.On("HttpInitiatePostRequest", mock.Anything, mock.Anything).Return(map[string]interface{}{}, errors.New("some error"))
+		}, wantErr: true},
+		{name: "should return error if update fails", setup: func(m *MockMonitorService) {
+			m.On("HandleRequest", mock.Anything, mock.Anything).Return([]map[string]interface{}{
+				{model.ErrorResultField: errors.New("some error")},
+			})
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testStruct := new(MockMonitorService)
+			tt.setup(testStruct)
+			got, err := createMonitor("", annotations, testStruct)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("createMonitor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("createMonitor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_updateMonitor(t *testing.T) {
+	annotations := map[string]string{
+		GetUptimeRobotDomain(): "true",
+		GetUptimeRobotMonitorPrefix() + httputil.FriendlyNameField: "example",
+		GetUptimeRobotMonitorPrefix() + httputil.TypeField:         "HTTP",
+	}
+	tests := []struct {
+		name    string
+		setup   func(m *MockMonitorService)
+		want    string
+		wantErr bool
+	}{
+		{name: "should update monitor by its id", setup: func(m *MockMonitorService) {
+			m.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, map[string]interface{}{httputil.MonitorsField: "1"}).Return(map[string]interface{}{
+				httputil.MonitorsField: []interface{}{map[string]interface{}{httputil.FriendlyNameField: "renamed", httputil.IdField: float64(1), httputil.TypeField: float64(1)}},
+			}, nil)
+			m.On("HandleRequest", mock.MatchedBy(func(dataMaps []map[string]interface{}) bool {
+				return dataMaps[0][httputil.IdField] == "1"
+			}), mock.Anything).Run(func(args mock.Arguments) {
+				args.Get(0).([]map[string]interface{})[0][httputil.TypeField] = 1
+			}).Return([]map[string]interface{}{})
+		}, want: "1"},
+		{name: "should look up id of monitor replaced on type change", setup: func(m *MockMonitorService) {
+			m.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, map[string]interface{}{httputil.MonitorsField: "1"}).Return(map[string]interface{}{
+				httputil.MonitorsField: []interface{}{map[string]interface{}{httputil.FriendlyNameField: "example", httputil.IdField: float64(1), httputil.TypeField: float64(2)}},
+			}, nil)
+			m.On("HandleRequest", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				args.Get(0).([]map[string]interface{})[0][httputil.TypeField] = 1
+			}).Return([]map[string]interface{}{})
+			m.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, map[string]interface{}{httputil.SearchField: "example"}).Return(map[string]interface{}{
+				httputil.MonitorsField: []interface{}{map[string]interface{}{httputil.FriendlyNameField: "example", httputil.IdField: float64(2)}},
+			}, nil)
+		}, want: "2"},
+		{name: "should create monitor by its friendly name when no monitor has the id", setup: func(m *MockMonitorService) {
+			m.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, map[string]interface{}{httputil.MonitorsField: "1"}).Return(map[string]interface{}{
+				httputil.MonitorsField: []interface{}{},
+			}, nil)
+			m.On("HandleRequest", mock.MatchedBy(func(dataMaps []map[string]interface{}) bool {
+				_, hasId := dataMaps[0][httputil.IdField]
+				return !hasId
+			}), mock.Anything).Run(func(args mock.Arguments) {
+				args.Get(0).([]map[string]interface{})[0][httputil.IdField] = 3
+			}).Return([]map[string]interface{}{})
+		}, want: "3"},
+		{name: "should return error if lookup fails", setup: func(m *MockMonitorService) {
+			m.On("HttpInitiatePostRequest", mock.Anything, mock.Anything).Return(map[string]interface{}(nil), errors.New("some error"))
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testStruct := new(MockMonitorService)
+			tt.setup(testStruct)
+			got, err := updateMonitor("1", "", annotations, testStruct)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("updateMonitor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("updateMonitor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
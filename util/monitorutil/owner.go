@@ -0,0 +1,137 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/model"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/service"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+const (
+	paginationField = "pagination"
+	// monitorsPageLimit is the maximum page size of getMonitors.
+	monitorsPageLimit = 50
+)
+
+// Owner is the resource a monitor was created for.
+type Owner struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// Monitor is a monitor of the UptimeRobot account.
+type Monitor struct {
+	Id           string
+	FriendlyName string
+	Url          string
+}
+
+// GetOwnerMarker returns the suffix added to the friendly name of the monitors created by the operator running in
+// the cluster identified by clusterId, it allows finding the monitors of resources that no longer exist.
+func GetOwnerMarker(clusterId string, owner Owner) string {
+	return fmt.Sprintf(" [%s/%s/%s/%s]", clusterId, owner.Kind, owner.Namespace, owner.Name)
+}
+
+// GetOwner returns the owner of a monitor created by the operator running in the cluster identified by clusterId.
+func GetOwner(friendlyName string, clusterId string) (Owner, bool) {
+	matches := regexp.MustCompile(` \[` + regexp.QuoteMeta(clusterId) + `/([^/\]]+)/([^/\]]*)/([^/\]]+)\]$`).FindStringSubmatch(friendlyName)
+	if matches == nil {
+		return Owner{}, false
+	}
+	return Owner{Kind: matches[1], Namespace: matches[2], Name: matches[3]}, true
+}
+
+// ListMonitors lists the monitors of the account whose friendly name or url contains search.
+func ListMonitors(ctx context.Context, apiKey string, search string) ([]Monitor, error) {
+	return listMonitors(search, newMonitorService(ctx, apiKey))
+}
+
+func listMonitors(search string, service service.IService) ([]Monitor, error) {
+	monitors := make([]Monitor, 0)
+	for offset, total := 0, 1; offset < total; offset += monitorsPageLimit {
+		resultMap, err := service.HttpInitiatePostRequest(httputil.GetMonitorsEndpoint, map[string]interface{}{
+			httputil.SearchField: search,
+			httputil.OffsetField: offset,
+			httputil.LimitField:  monitorsPageLimit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resultMap[httputil.StatField] != "ok" {
+			return nil, fmt.Errorf(`monitors not successfully listed: %v`, resultMap[httputil.ErrorField])
+		}
+		pagination, _ := resultMap[paginationField].(map[string]interface{})
+		total, err = strconv.Atoi(formatValue(pagination[httputil.TotalField]))
+		if err != nil {
+			return nil, err
+		}
+		results, _ := resultMap[httputil.MonitorsField].([]interface{})
+		for _, result := range results {
+			if _monitor, ok := result.(map[string]interface{}); ok {
+				monitors = append(monitors, Monitor{
+					Id:           formatValue(_monitor[httputil.IdField]),
+					FriendlyName: fmt.Sprint(_monitor[httputil.FriendlyNameField]),
+					Url:          fmt.Sprint(_monitor[httputil.UrlField]),
+				})
+			}
+		}
+	}
+	return monitors, nil
+}
+
+// DeleteMonitorById deletes the monitor with the id.
+func DeleteMonitorById(ctx context.Context, apiKey string, id string) error {
+	return deleteMonitorById(id, newMonitorService(ctx, apiKey))
+}
+
+func deleteMonitorById(id string, service service.IService) error {
+	resultArrayMap := service.HandleRequest([]map[string]interface{}{{httputil.IdField: id}}, model.Delete)
+	if len(resultArrayMap) > 0 && resultArrayMap[0] != nil && resultArrayMap[0][model.ErrorResultField] != nil {
+		return resultArrayMap[0][model.ErrorResultField].(error)
+	}
+	return nil
+}
+
+// SetMonitorPausedById pauses the monitor with the id, or resumes it when paused is false.
+func SetMonitorPausedById(ctx context.Context, apiKey string, id string, paused bool) error {
+	return setMonitorPausedById(id, paused, newMonitorService(ctx, apiKey))
+}
+
+func setMonitorPausedById(id string, paused bool, service service.IService) error {
+	status := 1
+	if paused {
+		status = 0
+	}
+	resultMap, err := service.HttpInitiatePostRequest(httputil.EditMonitorEndpoint, map[string]interface{}{
+		httputil.IdField: id,
+		statusField:      status,
+	})
+	if err != nil {
+		return err
+	}
+	if resultMap[httputil.StatField] != "ok" {
+		return fmt.Errorf(`monitor %s not successfully paused or resumed: %v`, id, resultMap[httputil.ErrorField])
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/model"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetOwner(t *testing.T) {
+	owner := Owner{Kind: "Ingress", Namespace: "default", Name: "web"}
+	tests := []struct {
+		name         string
+		friendlyName string
+		clusterId    string
+		want         Owner
+		wantFound    bool
+	}{
+		{name: "should parse owner marker", friendlyName: "tester" + GetOwnerMarker("prod", owner), clusterId: "prod", want: owner, wantFound: true},
+		{name: "should parse owner marker of cluster id with special characters", friendlyName: "tester" + GetOwnerMarker("prod.eu-1", owner), clusterId: "prod.eu-1", want: owner, wantFound: true},
+		{name: "should not parse owner marker of other cluster", friendlyName: "tester" + GetOwnerMarker("staging", owner), clusterId: "prod", wantFound: false},
+		{name: "should not parse unmarked friendly name", friendlyName: "tester", clusterId: "prod", wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := GetOwner(tt.friendlyName, tt.clusterId)
+			if found != tt.wantFound || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetOwner() = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func Test_listMonitors(t *testing.T) {
+	page := func(total int, ids ...float64) map[string]interface{} {
+		monitors := make([]interface{}, 0, len(ids))
+		for _, id := range ids {
+			monitors = append(monitors, map[string]interface{}{httputil.IdField: id, httputil.FriendlyNameField: "tester", httputil.UrlField: "https://test.localhost"})
+		}
+		return map[string]interface{}{
+			httputil.StatField:     "ok",
+			paginationField:        map[string]interface{}{httputil.TotalField: float64(total)},
+			httputil.MonitorsField: monitors,
+		}
+	}
+	testStruct := new(MockMonitorService)
+	testStruct.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, mock.MatchedBy(func(dataMap map[string]interface{}) bool {
+		return dataMap[httputil.OffsetField] == 0
+	})).Return(page(51, 777749809), nil)
+	testStruct.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, mock.MatchedBy(func(dataMap map[string]interface{}) bool {
+		return dataMap[httputil.OffsetField] == monitorsPageLimit
+	})).Return(page(51, 2), nil)
+
+	got, err := listMonitors("[prod/", testStruct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Monitor{
+		{Id: "777749809", FriendlyName: "tester", Url: "https://test.localhost"},
+		{Id: "2", FriendlyName: "tester", Url: "https://test.localhost"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("listMonitors() = %v, want %v", got, want)
+	}
+}
+
+func Test_listMonitorsShouldReturnErrorOnFailedRequest(t *testing.T) {
+	testStruct := new(MockMonitorService)
+	testStruct.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, mock.Anything).Return(map[string]interface{}{
+		httputil.StatField:  "fail",
+		httputil.ErrorField: map[string]interface{}{httputil.MessageField: "api_key is wrong"},
+	}, nil)
+	if _, err := listMonitors("", testStruct); err == nil {
+		t.Errorf("got %v ,  want error", err)
+	}
+}
+
+func Test_deleteMonitorById(t *testing.T) {
+	testStruct := new(MockMonitorService)
+	testStruct.On("HandleRequest", []map[string]interface{}{{httputil.IdField: "1"}}, mock.Anything).Return([]map[string]interface{}{
+		{model.ErrorResultField: errors.New("some error")},
+	})
+	if err := deleteMonitorById("1", testStruct); err == nil {
+		t.Errorf("got %v ,  want error", err)
+	}
+}
+
+func Test_setMonitorPausedById(t *testing.T) {
+	testStruct := new(MockMonitorService)
+	testStruct.On("HttpInitiatePostRequest", httputil.EditMonitorEndpoint, map[string]interface{}{httputil.IdField: "1", statusField: 0}).Return(map[string]interface{}{
+		httputil.StatField: "ok",
+	}, nil)
+	testStruct.On("HttpInitiatePostRequest", httputil.EditMonitorEndpoint, map[string]interface{}{httputil.IdField: "2", statusField: 1}).Return(map[string]interface{}{
+		httputil.StatField:  "fail",
+		httputil.ErrorField: map[string]interface{}{httputil.MessageField: "monitor not found"},
+	}, nil)
+	if err := setMonitorPausedById("1", true, testStruct); err != nil {
+		t.Errorf("got %v ,  want nil", err)
+	}
+	if err := setMonitorPausedById("2", false, testStruct); err == nil {
+		t.Errorf("got %v ,  want error", err)
+	}
+}
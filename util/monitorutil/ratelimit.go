@@ -0,0 +1,105 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// maxRateLimitedRetries is the number of times a request answered with 429 is sent again before failing.
+	maxRateLimitedRetries = 3
+	// retryAfterHeader is the header of 429 responses holding the delay before the next request.
+	retryAfterHeader = "Retry-After"
+)
+
+// defaultRetryAfter is the delay before the next request after a 429 response without Retry-After header.
+var defaultRetryAfter = time.Minute
+
+// rateLimiter queues the requests to the UptimeRobot api so that they wait for their turn rather than fail.
+type rateLimiter struct {
+	mu          sync.Mutex
+	limiter     *rate.Limiter
+	pausedUntil time.Time
+}
+
+// apiRateLimiter is shared by every request of the operator, it does not limit them until SetRateLimit is called.
+var apiRateLimiter = &rateLimiter{limiter: rate.NewLimiter(rate.Inf, 0)}
+
+// SetRateLimit limits the requests to the UptimeRobot api to requestsPerMinute, in bursts of at most burst
+// requests. Zero requestsPerMinute removes the limit.
+func SetRateLimit(requestsPerMinute int, burst int) {
+	limit := rate.Inf
+	if requestsPerMinute > 0 {
+		limit = rate.Limit(float64(requestsPerMinute) / time.Minute.Seconds())
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	apiRateLimiter.mu.Lock()
+	defer apiRateLimiter.mu.Unlock()
+	apiRateLimiter.limiter = rate.NewLimiter(limit, burst)
+}
+
+// wait blocks until the next request may be sent, or returns the error of ctx when it is done first.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		pause := time.Until(l.pausedUntil)
+		limiter := l.limiter
+		l.mu.Unlock()
+		if pause <= 0 {
+			return limiter.Wait(ctx)
+		}
+		timer := time.NewTimer(pause)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// pause holds back every request until delay elapsed, e.g. the Retry-After of a 429 response.
+func (l *rateLimiter) pause(delay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(delay); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// parseRetryAfter parses the Retry-After header of a 429 response, either a number of seconds or a date.
+func parseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+		return 0
+	}
+	return defaultRetryAfter
+}
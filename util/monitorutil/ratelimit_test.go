@@ -0,0 +1,140 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+func Test_parseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "should parse seconds", value: "30", want: 30 * time.Second},
+		{name: "should parse date in the past", value: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0},
+		{name: "should default missing header", value: "", want: defaultRetryAfter},
+		{name: "should default invalid header", value: "soon", want: defaultRetryAfter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value); got != tt.want {
+				t.Errorf("parseRetryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_apiClient_HttpInitiatePostRequest_rateLimited(t *testing.T) {
+	tests := []struct {
+		name         string
+		rateLimited  int
+		wantRequests int
+		wantErr      bool
+	}{
+		{name: "should retry rate limited request", rateLimited: 2, wantRequests: 3},
+		{name: "should return error when still rate limited", rateLimited: maxRateLimitedRetries + 1, wantRequests: maxRateLimitedRetries + 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= tt.rateLimited {
+					w.Header().Set(retryAfterHeader, "0")
+					w.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				_, _ = w.Write([]byte(`{"stat":"ok"}`))
+			}))
+			defer server.Close()
+			t.Setenv(httputil.UptimeRobotApiKeyEnv, "key")
+			t.Setenv(httputil.UptimeRobotApiUrlEnv, server.URL+"/")
+
+			client := &apiClient{httpClient: server.Client(), ctx: context.Background()}
+			_, err := client.HttpInitiatePostRequest(httputil.GetMonitorsEndpoint, map[string]interface{}{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HttpInitiatePostRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && ErrorClass(err) != ErrorClassRateLimited {
+				t.Errorf("ErrorClass() = %v, want %v", ErrorClass(err), ErrorClassRateLimited)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %v, want %v", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func Test_rateLimiter_wait(t *testing.T) {
+	SetRateLimit(600, 1)
+	defer SetRateLimit(0, 0)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_ = apiRateLimiter.wait(context.Background())
+	}
+	// the first request is sent right away, the next ones every 100ms.
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("3 requests sent within %v, want at least 200ms", elapsed)
+	}
+
+	SetRateLimit(0, 0)
+	apiRateLimiter.pause(100 * time.Millisecond)
+	start = time.Now()
+	_ = apiRateLimiter.wait(context.Background())
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("request sent within %v of the pause, want at least 100ms", elapsed)
+	}
+}
+
+func Test_rateLimiter_wait_canceled(t *testing.T) {
+	SetRateLimit(1, 1)
+	defer SetRateLimit(0, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// the first request takes the burst, the next one would wait for a minute.
+	if err := apiRateLimiter.wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiRateLimiter.wait(ctx); err == nil {
+		t.Error("wait() error = nil, want the error of the canceled context")
+	}
+
+	SetRateLimit(0, 0)
+	apiRateLimiter.pause(time.Minute)
+	defer func() {
+		apiRateLimiter.mu.Lock()
+		defer apiRateLimiter.mu.Unlock()
+		apiRateLimiter.pausedUntil = time.Time{}
+	}()
+	start := time.Now()
+	if err := apiRateLimiter.wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("wait() returned after %v of the pause, want it canceled", elapsed)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// AnnotationData is the data available to the templated values of the monitor annotations e.g.
+// friendly_name: "{{ .Namespace }}/{{ .Name }} {{ .Host }}" or url: "{{ .Scheme }}://{{ .Host }}/healthz".
+type AnnotationData struct {
+	Namespace string
+	Name      string
+	Labels    map[string]string
+	// Host monitored, a host of the resource or the address of the load balancer.
+	Host string
+	// Scheme of the monitor url, it is empty for monitors of a bare address e.g. Port monitors.
+	Scheme string
+	// Path of the ingress rule monitored through the paths annotation, it is empty otherwise.
+	Path string
+	// ClusterId is the --cluster-id of the operator.
+	ClusterId string
+}
+
+// IsTemplatedValue reports whether the annotation value is a template to render.
+func IsTemplatedValue(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// HasTemplatedValues reports whether any monitor annotation has a templated value.
+func HasTemplatedValues(annotations map[string]string) bool {
+	for key, value := range annotations {
+		if isRenderedAnnotation(key) && IsTemplatedValue(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAnnotationTemplate parses the templated value of an annotation, referencing missing data is an error.
+func ParseAnnotationTemplate(key string, value string) (*template.Template, error) {
+	return template.New(key).Option("missingkey=error").Parse(value)
+}
+
+// RenderAnnotations returns the annotations with their templated monitor parameters rendered with data.
+func RenderAnnotations(annotations map[string]string, data AnnotationData) (map[string]string, error) {
+	rendered := make(map[string]string, len(annotations))
+	for key, value := range annotations {
+		rendered[key] = value
+		if !isRenderedAnnotation(key) || !IsTemplatedValue(value) {
+			continue
+		}
+		tmpl, err := ParseAnnotationTemplate(key, value)
+		if err != nil {
+			return nil, err
+		}
+		var buffer bytes.Buffer
+		if err := tmpl.Execute(&buffer, data); err != nil {
+			return nil, fmt.Errorf("%s not successfully rendered: %w", key, err)
+		}
+		rendered[key] = buffer.String()
+	}
+	return rendered, nil
+}
+
+// isRenderedAnnotation reports whether the annotation is a monitor parameter or the path, the other annotations
+// configuring the operator are never rendered.
+func isRenderedAnnotation(key string) bool {
+	uptimeRobotPrefix := GetUptimeRobotMonitorPrefix()
+	if !strings.HasPrefix(key, uptimeRobotPrefix) {
+		return false
+	}
+	parameter, _, _ := strings.Cut(strings.TrimPrefix(key, uptimeRobotPrefix), ".")
+	return !statusAnnotations[parameter] && !isOperatorSetting(parameter)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+func TestRenderAnnotations(t *testing.T) {
+	prefix := GetUptimeRobotMonitorPrefix()
+	data := AnnotationData{Namespace: "default", Name: "web", Labels: map[string]string{"team": "a"}, Host: "a.localhost", Scheme: "https", ClusterId: "prod"}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        map[string]string
+		wantErr     bool
+	}{
+		{name: "should keep static values", annotations: map[string]string{
+			prefix + httputil.FriendlyNameField: "tester",
+		}, want: map[string]string{
+			prefix + httputil.FriendlyNameField: "tester",
+		}},
+		{name: "should render templated values", annotations: map[string]string{
+			prefix + httputil.FriendlyNameField:  "{{ .Namespace }}/{{ .Name }} {{ .Host }}",
+			prefix + Url:                         "{{ .Scheme }}://{{ .Host }}/healthz",
+			prefix + httputil.AlertContactsField: "{{ .Labels.team }}-{{ .ClusterId }}",
+		}, want: map[string]string{
+			prefix + httputil.FriendlyNameField:  "default/web a.localhost",
+			prefix + Url:                         "https://a.localhost/healthz",
+			prefix + httputil.AlertContactsField: "a-prod",
+		}},
+		{name: "should not render annotations of other tools nor the operator", annotations: map[string]string{
+			"example.com/template":               "{{ .Name }}",
+			GetStatusAnnotationKey(IdAnnotation): "{{ .Name }}",
+		}, want: map[string]string{
+			"example.com/template":               "{{ .Name }}",
+			GetStatusAnnotationKey(IdAnnotation): "{{ .Name }}",
+		}},
+		{name: "should return error on missing data", annotations: map[string]string{
+			prefix + httputil.FriendlyNameField: "{{ .Labels.missing }}",
+		}, wantErr: true},
+		{name: "should return error on invalid template", annotations: map[string]string{
+			prefix + httputil.FriendlyNameField: "{{ .Name }",
+		}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderAnnotations(tt.annotations, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderAnnotations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RenderAnnotations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/service"
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+// Fields of the monitors returned by getMonitors with their live status.
+const (
+	statusField              = "status"
+	responseTimesField       = "response_times"
+	averageResponseTimeField = "average_response_time"
+	allTimeUptimeRatioField  = "all_time_uptime_ratio"
+)
+
+// Status of an UptimeRobot monitor.
+const (
+	MonitorPaused        = 0
+	MonitorNotCheckedYet = 1
+	MonitorUp            = 2
+	MonitorSeemsDown     = 8
+	MonitorDown          = 9
+)
+
+// MonitorStatus is the live status of a monitor on UptimeRobot.
+type MonitorStatus struct {
+	Id  string
+	Url string
+	// Status is one of MonitorPaused, MonitorNotCheckedYet, MonitorUp, MonitorSeemsDown or MonitorDown.
+	Status int
+	// ResponseTime is the average response time in milliseconds.
+	ResponseTime float64
+	// UptimeRatio is the all time uptime ratio in percent.
+	UptimeRatio float64
+}
+
+// GetMonitorStatuses fetches the live status of the monitors with the ids, monitors that no longer exist are left
+// out. The monitors are fetched in batches of the page limit of getMonitors to spare the rate limit of the api.
+func GetMonitorStatuses(ctx context.Context, apiKey string, ids []string) ([]MonitorStatus, error) {
+	return getMonitorStatuses(ids, newMonitorService(ctx, apiKey))
+}
+
+func getMonitorStatuses(ids []string, service service.IService) ([]MonitorStatus, error) {
+	statuses := make([]MonitorStatus, 0, len(ids))
+	for start := 0; start < len(ids); start += monitorsPageLimit {
+		end := start + monitorsPageLimit
+		if end > len(ids) {
+			end = len(ids)
+		}
+		resultMap, err := service.HttpInitiatePostRequest(httputil.GetMonitorsEndpoint, map[string]interface{}{
+			httputil.MonitorsField:  strings.Join(ids[start:end], "-"),
+			httputil.LimitField:     monitorsPageLimit,
+			responseTimesField:      1,
+			allTimeUptimeRatioField: 1,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resultMap[httputil.StatField] != "ok" {
+			return nil, fmt.Errorf(`monitor statuses not successfully fetched: %v`, resultMap[httputil.ErrorField])
+		}
+		results, _ := resultMap[httputil.MonitorsField].([]interface{})
+		for _, result := range results {
+			if _monitor, ok := result.(map[string]interface{}); ok {
+				statuses = append(statuses, MonitorStatus{
+					Id:           formatValue(_monitor[httputil.IdField]),
+					Url:          fmt.Sprint(_monitor[httputil.UrlField]),
+					Status:       int(parseNumber(_monitor[statusField])),
+					ResponseTime: parseNumber(_monitor[averageResponseTimeField]),
+					UptimeRatio:  parseNumber(_monitor[allTimeUptimeRatioField]),
+				})
+			}
+		}
+	}
+	return statuses, nil
+}
+
+// parseNumber parses the numbers of the api which are returned either as numbers or as strings e.g. the uptime
+// ratios, missing or invalid numbers are zero.
+func parseNumber(value interface{}) float64 {
+	number, err := strconv.ParseFloat(formatValue(value), 64)
+	if err != nil {
+		return 0
+	}
+	return number
+}
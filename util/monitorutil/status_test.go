@@ -0,0 +1,67 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_getMonitorStatuses(t *testing.T) {
+	ids := make([]string, 0, monitorsPageLimit+1)
+	for id := 1; id <= monitorsPageLimit+1; id++ {
+		ids = append(ids, strconv.Itoa(id))
+	}
+	testStruct := new(MockMonitorService)
+	testStruct.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, mock.MatchedBy(func(dataMap map[string]interface{}) bool {
+		return strings.HasPrefix(dataMap[httputil.MonitorsField].(string), "1-2-")
+	})).Return(map[string]interface{}{httputil.StatField: "ok", httputil.MonitorsField: []interface{}{
+		map[string]interface{}{httputil.IdField: float64(1), httputil.UrlField: "https://a.local", statusField: float64(MonitorUp), averageResponseTimeField: "123.5", allTimeUptimeRatioField: "99.9"},
+	}}, nil)
+	testStruct.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, mock.MatchedBy(func(dataMap map[string]interface{}) bool {
+		return dataMap[httputil.MonitorsField] == strconv.Itoa(monitorsPageLimit+1)
+	})).Return(map[string]interface{}{httputil.StatField: "ok", httputil.MonitorsField: []interface{}{
+		map[string]interface{}{httputil.IdField: float64(monitorsPageLimit + 1), httputil.UrlField: "b.local", statusField: float64(MonitorDown)},
+	}}, nil)
+
+	got, err := getMonitorStatuses(ids, testStruct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []MonitorStatus{
+		{Id: "1", Url: "https://a.local", Status: MonitorUp, ResponseTime: 123.5, UptimeRatio: 99.9},
+		{Id: strconv.Itoa(monitorsPageLimit + 1), Url: "b.local", Status: MonitorDown},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("getMonitorStatuses() = %v, want %v", got, want)
+	}
+	testStruct.AssertNumberOfCalls(t, "HttpInitiatePostRequest", 2)
+}
+
+func Test_getMonitorStatuses_error(t *testing.T) {
+	testStruct := new(MockMonitorService)
+	testStruct.On("HttpInitiatePostRequest", httputil.GetMonitorsEndpoint, mock.Anything).Return(map[string]interface{}(nil), errors.New("some error"))
+	if _, err := getMonitorStatuses([]string{"1"}, testStruct); err == nil {
+		t.Error("getMonitorStatuses() error = nil, want error")
+	}
+}
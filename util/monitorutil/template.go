@@ -0,0 +1,38 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import "strings"
+
+// WithTemplateParameters returns the annotations with the monitor parameters of the templates of the resource
+// merged under them, a parameter of the templates only applies when the annotations do not set it. Templates are
+// not bound to hosts, so parameters suffixed with a host are ignored as are the status annotations and the
+// annotations which configure the operator.
+func WithTemplateParameters(annotations map[string]string, parameters map[string]string) map[string]string {
+	merged := make(map[string]string, len(annotations)+len(parameters))
+	uptimeRobotPrefix := GetUptimeRobotMonitorPrefix()
+	for key, value := range parameters {
+		if strings.Contains(key, ".") || statusAnnotations[key] || isOperatorSetting(key) {
+			continue
+		}
+		merged[uptimeRobotPrefix+key] = value
+	}
+	for key, value := range annotations {
+		merged[key] = value
+	}
+	return merged
+}
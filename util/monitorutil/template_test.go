@@ -0,0 +1,79 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+func TestWithTemplateParameters(t *testing.T) {
+	prefix := GetUptimeRobotMonitorPrefix()
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		parameters  map[string]string
+		want        map[string]string
+	}{
+		{name: "should keep annotations without parameters", annotations: map[string]string{prefix + Interval: "300"}, parameters: nil, want: map[string]string{prefix + Interval: "300"}},
+		{name: "should merge parameters under annotations", annotations: map[string]string{
+			GetUptimeRobotDomain(): "true",
+			prefix + Interval:      "300",
+		}, parameters: map[string]string{
+			Interval:                    "60",
+			httputil.AlertContactsField: "on-call",
+		}, want: map[string]string{
+			GetUptimeRobotDomain():               "true",
+			prefix + Interval:                    "300",
+			prefix + httputil.AlertContactsField: "on-call",
+		}},
+		{name: "should skip parameters configuring the operator", annotations: map[string]string{}, parameters: map[string]string{
+			StatusAnnotation:       StatusSynced,
+			ApiKeySecretAnnotation: "uptimerobot/api-key",
+			TemplateAnnotation:     "other",
+			Interval + ".a.local":  "60",
+			Timeout:                "30",
+		}, want: map[string]string{
+			prefix + Timeout: "30",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WithTemplateParameters(tt.annotations, tt.parameters); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WithTemplateParameters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTemplateParameters_operatorAnnotations(t *testing.T) {
+	parameters := map[string]string{}
+	for parameter := range operatorAnnotations {
+		parameters[parameter] = "value"
+	}
+	want := map[string]string{GetUptimeRobotMonitorPrefix() + PathAnnotation: "value"}
+	if got := WithTemplateParameters(nil, parameters); !reflect.DeepEqual(got, want) {
+		t.Errorf("WithTemplateParameters() = %v, want only the path of the operator annotations", got)
+	}
+	for parameter := range operatorAnnotations {
+		if got := isRenderedAnnotation(GetUptimeRobotMonitorPrefix() + parameter); got != (parameter == PathAnnotation) {
+			t.Errorf("isRenderedAnnotation(%s) = %v", parameter, got)
+		}
+	}
+}
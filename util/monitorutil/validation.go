@@ -0,0 +1,258 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+// DefaultMinInterval is the shortest monitoring interval in seconds of the free UptimeRobot plan.
+const DefaultMinInterval = 300
+
+// maxTimeout is the longest timeout in seconds accepted by UptimeRobot.
+const maxTimeout = 60
+
+// parameterValidators validates the value of each monitor parameter that can be set through the annotations,
+// parameters without constraints on their value are validated by nil.
+var parameterValidators = map[string]func(value string) error{
+	httputil.FriendlyNameField:            nil,
+	httputil.UrlField:                     validateUrl,
+	httputil.TypeField:                    validateResolvedValue(httputil.TypeField),
+	httputil.SubTypeField:                 validateResolvedValue(httputil.SubTypeField),
+	httputil.PortField:                    validateRange(1, 65535),
+	httputil.KeywordTypeField:             validateResolvedValue(httputil.KeywordTypeField),
+	httputil.KeywordCaseTypeField:         validateResolvedValue(httputil.KeywordCaseTypeField),
+	httputil.KeywordValueField:            nil,
+	Interval:                              nil,
+	Timeout:                               validateRange(1, maxTimeout),
+	"http_username":                       nil,
+	"http_password":                       nil,
+	httputil.HttpAuthTypeField:            validateResolvedValue(httputil.HttpAuthTypeField),
+	httputil.HttpMethodField:              validateUnsupported,
+	"post_type":                           nil,
+	httputil.PostValueField:               validateUnsupported,
+	httputil.PostContentTypeField:         validateUnsupported,
+	httputil.AlertContactsField:           nil,
+	"mwindows":                            nil,
+	"custom_http_headers":                 nil,
+	"custom_http_statuses":                nil,
+	"ignore_ssl_errors":                   validateOneOf("0", "1"),
+	"disable_domain_expire_notifications": validateOneOf("0", "1"),
+	ApiKeySecretAnnotation:                validateApiKeySecret,
+	TemplateAnnotation:                    nil,
+	PathAnnotation:                        validatePath,
+	PathsAnnotation:                       validatePattern,
+	OnDisableAnnotation:                   validateOneOf(OnDisableDelete, OnDisablePause),
+	AdoptAnnotation:                       validateOneOf("true", "false", AdoptPreview),
+	AdoptIdAnnotation:                     validateRange(1, math.MaxInt),
+}
+
+// ValidateAnnotations checks the monitor annotations against the parameters known to UptimeRobot and their
+// values, minInterval is the shortest interval allowed by the plan of the account. It returns a problem for each
+// invalid annotation, the annotations of other tools are ignored.
+func ValidateAnnotations(annotations map[string]string, minInterval int) []error {
+	return validateAnnotations(annotations, minInterval, func(string) bool { return true })
+}
+
+// ValidateAnnotationChanges only checks the annotations whose value changed from oldAnnotations, so that annotations
+// admitted before they became invalid, e.g. before the validation existed, do not block the other updates of a
+// resource. The interval and timeout of a host are checked together once either of them changed.
+func ValidateAnnotationChanges(oldAnnotations map[string]string, annotations map[string]string, minInterval int) []error {
+	return validateAnnotations(annotations, minInterval, func(key string) bool {
+		oldValue, existed := oldAnnotations[key]
+		value, exists := annotations[key]
+		return existed != exists || oldValue != value
+	})
+}
+
+// validateAnnotations checks the annotations for which changed returns true.
+func validateAnnotations(annotations map[string]string, minInterval int, changed func(key string) bool) []error {
+	problems := make([]error, 0)
+	if value, exists := annotations[GetUptimeRobotDomain()]; exists && changed(GetUptimeRobotDomain()) {
+		if _, err := strconv.ParseBool(value); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %q is not a boolean", GetUptimeRobotDomain(), value))
+		}
+	}
+
+	uptimeRobotPrefix := GetUptimeRobotMonitorPrefix()
+	hosts := map[string]bool{"": true}
+	for _, key := range sortedAnnotationKeys(annotations) {
+		if !strings.HasPrefix(key, uptimeRobotPrefix) || statusAnnotations[strings.TrimPrefix(key, uptimeRobotPrefix)] {
+			continue
+		}
+		parameter, host, _ := strings.Cut(strings.TrimPrefix(key, uptimeRobotPrefix), ".")
+		validator, known := parameterValidators[parameter]
+		if !known {
+			if changed(key) {
+				problems = append(problems, fmt.Errorf("%s: unknown monitor parameter %q", key, parameter))
+			}
+			continue
+		}
+		hosts[host] = true
+		if !changed(key) {
+			continue
+		}
+		// templated values are only known once rendered for each host, only their syntax is checked.
+		if IsTemplatedValue(annotations[key]) {
+			if _, err := ParseAnnotationTemplate(key, annotations[key]); err != nil {
+				problems = append(problems, fmt.Errorf("%s: %w", key, err))
+			}
+			continue
+		}
+		if validator != nil {
+			if err := validator(annotations[key]); err != nil {
+				problems = append(problems, fmt.Errorf("%s: %w", key, err))
+			}
+		}
+	}
+
+	// interval and timeout are checked on the annotations of each host since they can be overridden separately.
+	for _, host := range sortedHosts(hosts) {
+		if !changed(uptimeRobotPrefix+Interval) && !changed(uptimeRobotPrefix+Timeout) &&
+			(len(host) == 0 || !changed(uptimeRobotPrefix+Interval+"."+host) && !changed(uptimeRobotPrefix+Timeout+"."+host)) {
+			continue
+		}
+		hostAnnotations := annotations
+		if len(host) > 0 {
+			_, intervalOverridden := annotations[uptimeRobotPrefix+Interval+"."+host]
+			_, timeoutOverridden := annotations[uptimeRobotPrefix+Timeout+"."+host]
+			if !intervalOverridden && !timeoutOverridden {
+				continue
+			}
+			hostAnnotations = GetHostAnnotations(annotations, host)
+		}
+		problems = append(problems, validateIntervalAndTimeout(hostAnnotations, minInterval, host)...)
+	}
+	return problems
+}
+
+func validateIntervalAndTimeout(annotations map[string]string, minInterval int, host string) []error {
+	problems := make([]error, 0)
+	suffix := ""
+	if len(host) > 0 {
+		suffix = " of " + host
+	}
+	intervalValue, intervalExists := annotations[GetUptimeRobotMonitorPrefix()+Interval]
+	timeoutValue, timeoutExists := annotations[GetUptimeRobotMonitorPrefix()+Timeout]
+	if !intervalExists || IsTemplatedValue(intervalValue) {
+		return problems
+	}
+	interval, err := strconv.Atoi(intervalValue)
+	if err != nil {
+		return append(problems, fmt.Errorf("%s%s: %q is not a number", Interval, suffix, intervalValue))
+	}
+	if interval < minInterval {
+		problems = append(problems, fmt.Errorf("%s%s: %d is shorter than the minimum interval %d", Interval, suffix, interval, minInterval))
+	}
+	if timeout, err := strconv.Atoi(timeoutValue); timeoutExists && err == nil && timeout > interval {
+		problems = append(problems, fmt.Errorf("%s%s: %d is longer than the interval %d", Timeout, suffix, timeout, interval))
+	}
+	return problems
+}
+
+func validateUrl(value string) error {
+	if _, err := url.ParseRequestURI(value); err != nil {
+		return fmt.Errorf("%q is not a valid url", value)
+	}
+	return nil
+}
+
+func validateResolvedValue(parameter string) func(value string) error {
+	return func(value string) error {
+		if _, exists := resolvedValues[parameter][value]; !exists {
+			return fmt.Errorf("%q is not one of %v", value, sortedAnnotationKeys(resolvedValues[parameter]))
+		}
+		return nil
+	}
+}
+
+func validateRange(min int, max int) func(value string) error {
+	return func(value string) error {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		if number < min || number > max {
+			return fmt.Errorf("%d is not between %d and %d", number, min, max)
+		}
+		return nil
+	}
+}
+
+func validateOneOf(values ...string) func(value string) error {
+	return func(value string) error {
+		for _, allowed := range values {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %v", value, values)
+	}
+}
+
+// validateUnsupported rejects the parameters dropped from the requests to the api, they would have no effect.
+func validateUnsupported(string) error {
+	return errors.New("is not supported, it is not sent to UptimeRobot")
+}
+
+func validateApiKeySecret(value string) error {
+	if name, key, found := strings.Cut(value, "/"); !found || len(name) == 0 || len(key) == 0 {
+		return fmt.Errorf("%q is not of the form <secret name>/<key>", value)
+	}
+	return nil
+}
+
+func validatePath(value string) error {
+	if _, err := url.ParseRequestURI(value); err != nil || !strings.HasPrefix(value, "/") {
+		return fmt.Errorf("%q is not a valid path", value)
+	}
+	return nil
+}
+
+func validatePattern(value string) error {
+	if _, err := regexp.Compile(value); err != nil {
+		return fmt.Errorf("%q is not a valid regular expression", value)
+	}
+	return nil
+}
+
+func sortedAnnotationKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedHosts(hosts map[string]bool) []string {
+	keys := make([]string, 0, len(hosts))
+	for host := range hosts {
+		keys = append(keys, host)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package monitorutil
+
+import (
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-tooling/pkg/util/httputil"
+)
+
+func TestValidateAnnotations(t *testing.T) {
+	prefix := GetUptimeRobotMonitorPrefix()
+	tests := []struct {
+		name         string
+		annotations  map[string]string
+		wantProblems int
+	}{
+		{name: "should accept valid annotations", annotations: map[string]string{
+			GetUptimeRobotDomain():                         "true",
+			prefix + httputil.FriendlyNameField:            "tester",
+			prefix + httputil.TypeField:                    "Keyword",
+			prefix + httputil.KeywordTypeField:             "exists",
+			prefix + httputil.KeywordValueField:            "healthy",
+			prefix + Interval:                              "300",
+			prefix + Timeout:                               "30",
+			prefix + Interval + ".a.localhost":             "600",
+			prefix + httputil.FriendlyNameField + ".b.com": "b",
+			GetStatusAnnotationKey(StatusAnnotation):       StatusSynced,
+			"kubernetes.io/ingress.class":                  "nginx",
+		}, wantProblems: 0},
+		{name: "should reject unknown parameters", annotations: map[string]string{
+			prefix + "intervall": "300",
+		}, wantProblems: 1},
+		{name: "should reject invalid enable flag", annotations: map[string]string{
+			GetUptimeRobotDomain(): "yes please",
+		}, wantProblems: 1},
+		{name: "should reject non numeric interval", annotations: map[string]string{
+			prefix + Interval: "sixty",
+		}, wantProblems: 1},
+		{name: "should reject interval shorter than the minimum", annotations: map[string]string{
+			prefix + Interval:                  "300",
+			prefix + Interval + ".a.localhost": "60",
+		}, wantProblems: 1},
+		{name: "should reject timeout longer than the interval", annotations: map[string]string{
+			prefix + Interval:                  "300",
+			prefix + Timeout:                   "60",
+			prefix + Interval + ".a.localhost": "30",
+		}, wantProblems: 2},
+		{name: "should reject invalid values", annotations: map[string]string{
+			prefix + httputil.TypeField:     "HTTP2",
+			prefix + httputil.PortField:     "0",
+			prefix + Timeout:                "61",
+			prefix + ApiKeySecretAnnotation: "secret",
+			prefix + httputil.UrlField:      "not a url",
+		}, wantProblems: 5},
+		{name: "should reject parameters that are not sent", annotations: map[string]string{
+			prefix + httputil.HttpMethodField:      "GET",
+			prefix + httputil.PostValueField:       "{}",
+			prefix + httputil.PostContentTypeField: "0",
+		}, wantProblems: 3},
+		{name: "should reject unknown on-disable policy", annotations: map[string]string{
+			prefix + OnDisableAnnotation: "archive",
+		}, wantProblems: 1},
+		{name: "should reject invalid adoption", annotations: map[string]string{
+			prefix + AdoptAnnotation:   "yes",
+			prefix + AdoptIdAnnotation: "m12345",
+		}, wantProblems: 2},
+		{name: "should accept templated values", annotations: map[string]string{
+			prefix + httputil.FriendlyNameField: "{{ .Namespace }}/{{ .Name }}",
+			prefix + httputil.UrlField:          "{{ .Scheme }}://{{ .Host }}/healthz",
+		}, wantProblems: 0},
+		{name: "should reject invalid paths", annotations: map[string]string{
+			prefix + PathAnnotation:  "healthz",
+			prefix + PathsAnnotation: "(",
+		}, wantProblems: 2},
+		{name: "should reject invalid templates", annotations: map[string]string{
+			prefix + httputil.UrlField: "{{ .Scheme }://{{ .Host }}",
+		}, wantProblems: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateAnnotations(tt.annotations, DefaultMinInterval); len(got) != tt.wantProblems {
+				t.Errorf("ValidateAnnotations() = %v, want %d problems", got, tt.wantProblems)
+			}
+		})
+	}
+}
+
+func TestValidateAnnotationChanges(t *testing.T) {
+	prefix := GetUptimeRobotMonitorPrefix()
+	admitted := map[string]string{
+		prefix + "intervall":          "300",
+		prefix + Interval:             "60",
+		prefix + httputil.UrlField:    "https://a.local",
+		GetUptimeRobotDomain():        "true",
+		prefix + Timeout + ".a.local": "30",
+	}
+	tests := []struct {
+		name         string
+		annotations  map[string]string
+		wantProblems int
+	}{
+		{name: "should accept unchanged invalid annotations", annotations: admitted, wantProblems: 0},
+		{name: "should reject changed invalid annotations", annotations: map[string]string{
+			prefix + "intervall":          "300",
+			prefix + Interval:             "60",
+			prefix + httputil.UrlField:    "not a url",
+			GetUptimeRobotDomain():        "yes",
+			prefix + Timeout + ".a.local": "30",
+		}, wantProblems: 2},
+		{name: "should check interval and timeout of a host once one of them changed", annotations: map[string]string{
+			prefix + "intervall":          "300",
+			prefix + Interval:             "60",
+			prefix + httputil.UrlField:    "https://a.local",
+			GetUptimeRobotDomain():        "true",
+			prefix + Timeout + ".a.local": "90",
+		}, wantProblems: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateAnnotationChanges(admitted, tt.annotations, DefaultMinInterval); len(got) != tt.wantProblems {
+				t.Errorf("ValidateAnnotationChanges() = %v, want %d problems", got, tt.wantProblems)
+			}
+		})
+	}
+}
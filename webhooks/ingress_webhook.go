@@ -0,0 +1,97 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	admissionv1 "k8s.io/api/admission/v1"
+	network "k8s.io/api/networking/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// IngressValidatorPath is the path the ingress validating webhook is served on.
+const IngressValidatorPath = "/validate-networking-k8s-io-v1-ingress"
+
+//+kubebuilder:webhook:path=/validate-networking-k8s-io-v1-ingress,mutating=false,failurePolicy=ignore,sideEffects=None,groups=networking.k8s.io,resources=ingresses,verbs=create;update,versions=v1,name=vingress.bennsimon.github.io,admissionReviewVersions=v1
+
+// IngressValidator validates the uptimerobot-monitor annotations of ingresses on admission, so that typos are
+// reported to the user instead of failing at the UptimeRobot api.
+type IngressValidator struct {
+	// MinInterval is the shortest interval in seconds allowed by the plan of the UptimeRobot account.
+	MinInterval int
+	// WarnOnly admits ingresses with invalid annotations and returns the problems as warnings.
+	WarnOnly bool
+	decoder  *admission.Decoder
+}
+
+var _ admission.Handler = &IngressValidator{}
+var _ admission.DecoderInjector = &IngressValidator{}
+
+func (v *IngressValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	ingress := &network.Ingress{}
+	if err := v.decoder.Decode(req, ingress); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// the finalizer of a deleted ingress is removed by an update, it is never denied.
+	if ingress.DeletionTimestamp != nil {
+		return admission.Allowed("")
+	}
+	var problems []error
+	if req.Operation == admissionv1.Update && len(req.OldObject.Raw) > 0 {
+		oldIngress := &network.Ingress{}
+		if err := v.decoder.DecodeRaw(req.OldObject, oldIngress); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		problems = monitorutil.ValidateAnnotationChanges(oldIngress.Annotations, ingress.Annotations, v.MinInterval)
+	} else {
+		problems = monitorutil.ValidateAnnotations(ingress.Annotations, v.MinInterval)
+	}
+	if len(problems) == 0 {
+		return admission.Allowed("")
+	}
+	messages := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		messages = append(messages, problem.Error())
+	}
+	if v.WarnOnly {
+		return admission.Allowed("").WithWarnings(messages...)
+	}
+	return admission.Denied("invalid uptimerobot-monitor annotations: " + strings.Join(messages, "; "))
+}
+
+func (v *IngressValidator) InjectDecoder(decoder *admission.Decoder) error {
+	v.decoder = decoder
+	return nil
+}
+
+// SetupWithManager registers the webhook on the webhook server of the Manager.
+func (v *IngressValidator) SetupWithManager(mgr ctrl.Manager) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+	v.decoder = decoder
+	mgr.GetWebhookServer().Register(IngressValidatorPath, &webhook.Admission{Handler: v})
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/bennsimon/uptimerobot-operator/util/monitorutil"
+	admissionv1 "k8s.io/api/admission/v1"
+	network "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func newIngressRaw(t *testing.T, annotations map[string]string, deleting bool) runtime.RawExtension {
+	ingress := &network.Ingress{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "networking.k8s.io/v1", Kind: "Ingress"},
+		ObjectMeta: metav1.ObjectMeta{Name: "tester", Namespace: "default", Annotations: annotations},
+	}
+	if deleting {
+		now := metav1.Now()
+		ingress.DeletionTimestamp = &now
+	}
+	raw, err := json.Marshal(ingress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime.RawExtension{Raw: raw}
+}
+
+func newRequest(t *testing.T, annotations map[string]string) admission.Request {
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Object:    newIngressRaw(t, annotations, false),
+	}}
+}
+
+func newUpdateRequest(t *testing.T, oldAnnotations map[string]string, annotations map[string]string, deleting bool) admission.Request {
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Update,
+		Object:    newIngressRaw(t, annotations, deleting),
+		OldObject: newIngressRaw(t, oldAnnotations, false),
+	}}
+}
+
+func TestIngressValidator_Handle(t *testing.T) {
+	prefix := monitorutil.GetUptimeRobotMonitorPrefix()
+	valid := map[string]string{
+		monitorutil.GetUptimeRobotDomain(): "true",
+		prefix + monitorutil.Interval:      "300",
+	}
+	invalid := map[string]string{
+		monitorutil.GetUptimeRobotDomain(): "true",
+		prefix + "intervall":               "300",
+	}
+	invalidSynced := map[string]string{
+		monitorutil.GetStatusAnnotationKey(monitorutil.StatusAnnotation): monitorutil.StatusSynced,
+	}
+	for key, value := range invalid {
+		invalidSynced[key] = value
+	}
+	invalidInterval := map[string]string{
+		monitorutil.GetUptimeRobotDomain(): "true",
+		prefix + monitorutil.Interval:      "60",
+	}
+	tests := []struct {
+		name         string
+		warnOnly     bool
+		annotations  map[string]string
+		request      func(t *testing.T) admission.Request
+		wantAllowed  bool
+		wantWarnings int
+	}{
+		{name: "should admit valid annotations", annotations: valid, wantAllowed: true},
+		{name: "should admit ingresses without monitor", annotations: nil, wantAllowed: true},
+		{name: "should deny invalid annotations", annotations: invalid, wantAllowed: false},
+		{name: "should warn on invalid annotations", warnOnly: true, annotations: invalid, wantAllowed: true, wantWarnings: 1},
+		{name: "should admit updates leaving invalid annotations unchanged", request: func(t *testing.T) admission.Request {
+			return newUpdateRequest(t, invalid, invalidSynced, false)
+		}, wantAllowed: true},
+		{name: "should deny updates changing annotations to invalid values", request: func(t *testing.T) admission.Request {
+			return newUpdateRequest(t, valid, invalidInterval, false)
+		}, wantAllowed: false},
+		{name: "should admit updates of terminating ingresses", request: func(t *testing.T) admission.Request {
+			return newUpdateRequest(t, valid, invalid, true)
+		}, wantAllowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder, err := admission.NewDecoder(scheme.Scheme)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v := &IngressValidator{MinInterval: monitorutil.DefaultMinInterval, WarnOnly: tt.warnOnly}
+			if err := v.InjectDecoder(decoder); err != nil {
+				t.Fatal(err)
+			}
+			req := newRequest(t, tt.annotations)
+			if tt.request != nil {
+				req = tt.request(t)
+			}
+			got := v.Handle(context.Background(), req)
+			if got.Allowed != tt.wantAllowed || len(got.Warnings) != tt.wantWarnings {
+				t.Errorf("Handle() = %v, warnings %v", got.Result, got.Warnings)
+			}
+		})
+	}
+}